@@ -2,6 +2,9 @@ module github.com/bherville/curseforge-sdk-go
 
 go 1.20
 
-require github.com/sirupsen/logrus v1.9.3
+require (
+	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/net v0.33.0
+)
 
-require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,437 @@
+package curseforge
+
+import (
+"encoding/json"
+"errors"
+"fmt"
+"io/fs"
+"os"
+"path/filepath"
+"sort"
+"strings"
+"sync"
+)
+
+// Layout of a BlobStore root directory
+const (
+blobStoreBlobsDir    = "blobs"
+blobStoreTmpDir      = "tmp"
+blobStoreRegistry    = "instances.json"
+blobStoreBlobPerm    = 0o444
+blobStoreRegistryVer = 1
+)
+
+// BlobInstance is an instance directory registered with a BlobStore
+// Files maps each installed destination path to the SHA1 of its blob
+type BlobInstance struct {
+Dir   string            `json:"dir"`
+Files map[string]string `json:"files"`
+}
+
+// blobRegistry is the on-disk list of instances and their references
+type blobRegistry struct {
+Version   int                      `json:"version"`
+Instances map[string]*BlobInstance `json:"instances"`
+}
+
+// BlobStore is a local content-addressed store of downloaded files keyed by
+// SHA1 (from File.Hashes) that can be shared by many instances
+// Blobs are installed into instances by hardlink, falling back to a copy when
+// the instance lives on another filesystem. Blobs are read-only; a hardlinked
+// file modified in place would change every instance sharing it.
+// The store always works on the OS filesystem; DownloadOptions.FS is ignored.
+// Set DownloadOptions.Store to have DownloadFileWithOptions and DownloadFiles
+// go through the store.
+type BlobStore struct {
+root     string
+mu       sync.Mutex
+registry blobRegistry
+fetching map[string]*blobLock // only blobs being fetched or installed
+}
+
+// blobLock serializes the work on one blob; it is dropped from
+// BlobStore.fetching once no goroutine holds or waits for it
+type blobLock struct {
+mu   sync.Mutex
+refs int
+}
+
+// OpenBlobStore opens (creating if needed) the blob store at root
+func OpenBlobStore(root string) (*BlobStore, error) {
+for _, dir := range []string{blobStoreBlobsDir, blobStoreTmpDir} {
+if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+return nil, err
+}
+}
+
+store := &BlobStore{
+root:     root,
+registry: blobRegistry{Version: blobStoreRegistryVer, Instances: make(map[string]*BlobInstance)},
+fetching: make(map[string]*blobLock),
+}
+
+data, err := os.ReadFile(filepath.Join(root, blobStoreRegistry))
+if errors.Is(err, os.ErrNotExist) {
+return store, nil
+}
+if err != nil {
+return nil, fmt.Errorf("failed to read blob store registry: %w", err)
+}
+if err := json.Unmarshal(data, &store.registry); err != nil {
+return nil, fmt.Errorf("failed to unmarshal blob store registry: %w", err)
+}
+if store.registry.Instances == nil {
+store.registry.Instances = make(map[string]*BlobInstance)
+}
+return store, nil
+}
+
+// BlobPath returns where the blob with the given SHA1 is stored
+func (s *BlobStore) BlobPath(sha1 string) string {
+sha1 = strings.ToLower(sha1)
+prefix := sha1
+if len(prefix) > 2 {
+prefix = prefix[:2]
+}
+return filepath.Join(s.root, blobStoreBlobsDir, prefix, sha1)
+}
+
+// Has reports whether the blob with the given SHA1 is stored
+func (s *BlobStore) Has(sha1 string) bool {
+_, err := os.Stat(s.BlobPath(sha1))
+return err == nil
+}
+
+// lockBlob takes the lock serializing the work on one blob and returns the
+// function releasing it
+func (s *BlobStore) lockBlob(sha1 string) func() {
+s.mu.Lock()
+lock, ok := s.fetching[sha1]
+if !ok {
+lock = &blobLock{}
+s.fetching[sha1] = lock
+}
+lock.refs++
+s.mu.Unlock()
+
+lock.mu.Lock()
+return func() {
+lock.mu.Unlock()
+s.mu.Lock()
+lock.refs--
+if lock.refs == 0 {
+delete(s.fetching, sha1)
+}
+s.mu.Unlock()
+}
+}
+
+// Fetch returns the blob path for file, downloading it only if no blob with
+// its SHA1 is stored yet
+func (s *BlobStore) Fetch(file File, options DownloadOptions) (string, error) {
+sha1 := strings.ToLower(GetSha1Hash(file))
+if sha1 == "" {
+return "", fmt.Errorf("file %d has no SHA1 hash to store it by", file.ID)
+}
+
+unlock := s.lockBlob(sha1)
+defer unlock()
+
+return s.fetchLocked(file, sha1, options)
+}
+
+// fetchLocked implements Fetch; callers hold the blob's fetch lock
+func (s *BlobStore) fetchLocked(file File, sha1 string, options DownloadOptions) (string, error) {
+blobPath := s.BlobPath(sha1)
+if s.Has(sha1) {
+contextLogger.Trace(fmt.Sprintf("blob store hit for %s (%s)", file.FileName, sha1))
+return blobPath, nil
+}
+
+tmpPath := filepath.Join(s.root, blobStoreTmpDir, sha1)
+options.FS = OSFS{}
+options.Store = nil
+if err := DownloadFileWithOptions(file, tmpPath, options); err != nil {
+return "", err
+}
+return blobPath, s.storeBlob(tmpPath, blobPath)
+}
+
+// Add imports an existing local file into the store and returns its SHA1
+func (s *BlobStore) Add(path string) (string, error) {
+hashes, err := ComputeFileHashes(path)
+if err != nil {
+return "", err
+}
+
+unlock := s.lockBlob(hashes.Sha1)
+defer unlock()
+
+if s.Has(hashes.Sha1) {
+return hashes.Sha1, nil
+}
+
+tmpPath := filepath.Join(s.root, blobStoreTmpDir, hashes.Sha1)
+if err := copyFileFS(OSFS{}, path, OSFS{}, tmpPath); err != nil {
+return "", err
+}
+return hashes.Sha1, s.storeBlob(tmpPath, s.BlobPath(hashes.Sha1))
+}
+
+// storeBlob moves a verified temporary file into the blob directory
+func (s *BlobStore) storeBlob(tmpPath string, blobPath string) error {
+if err := os.MkdirAll(filepath.Dir(blobPath), 0o755); err != nil {
+return err
+}
+if err := os.Chmod(tmpPath, blobStoreBlobPerm); err != nil {
+return err
+}
+return os.Rename(tmpPath, blobPath)
+}
+
+// RegisterInstance records an instance directory so its files keep their blobs alive
+// Registering an existing name updates its directory and keeps its references
+func (s *BlobStore) RegisterInstance(name string, dir string) error {
+absDir, err := filepath.Abs(dir)
+if err != nil {
+return err
+}
+
+s.mu.Lock()
+defer s.mu.Unlock()
+
+if instance, ok := s.registry.Instances[name]; ok {
+instance.Dir = absDir
+} else {
+s.registry.Instances[name] = &BlobInstance{Dir: absDir, Files: make(map[string]string)}
+}
+return s.saveRegistry()
+}
+
+// UnregisterInstance forgets an instance; its blobs become collectable
+func (s *BlobStore) UnregisterInstance(name string) error {
+s.mu.Lock()
+defer s.mu.Unlock()
+
+delete(s.registry.Instances, name)
+return s.saveRegistry()
+}
+
+// Instances returns the names of the registered instances
+func (s *BlobStore) Instances() []string {
+s.mu.Lock()
+defer s.mu.Unlock()
+
+names := make([]string, 0, len(s.registry.Instances))
+for name := range s.registry.Instances {
+names = append(names, name)
+}
+sort.Strings(names)
+return names
+}
+
+// InstallFile fetches file through the store and installs it at destination
+// inside a registered instance, recording the reference
+// destination may be absolute or relative to the instance directory
+func (s *BlobStore) InstallFile(instanceName string, file File, destination string, options DownloadOptions) error {
+instanceDir, err := s.instanceDir(instanceName)
+if err != nil {
+return err
+}
+if !filepath.IsAbs(destination) {
+destination = filepath.Join(instanceDir, destination)
+}
+
+return s.installBlob(instanceName, file, destination, options, func(blobPath string) error {
+return linkOrCopyBlob(blobPath, destination)
+})
+}
+
+// instanceDir returns the directory of a registered instance
+func (s *BlobStore) instanceDir(instanceName string) (string, error) {
+s.mu.Lock()
+defer s.mu.Unlock()
+
+instance, ok := s.registry.Instances[instanceName]
+if !ok {
+return "", fmt.Errorf("instance %q is not registered", instanceName)
+}
+return instance.Dir, nil
+}
+
+// installBlob fetches file, places it with place and records reference
+// against the instance
+// The blob lock is held until the reference is recorded so a concurrent
+// GarbageCollect cannot remove the blob in between
+func (s *BlobStore) installBlob(instanceName string, file File, reference string, options DownloadOptions, place func(blobPath string) error) error {
+sha1 := strings.ToLower(GetSha1Hash(file))
+if sha1 == "" {
+return fmt.Errorf("file %d has no SHA1 hash to store it by", file.ID)
+}
+
+unlock := s.lockBlob(sha1)
+defer unlock()
+
+blobPath, err := s.fetchLocked(file, sha1, options)
+if err != nil {
+return err
+}
+if err := place(blobPath); err != nil {
+return err
+}
+
+s.mu.Lock()
+defer s.mu.Unlock()
+instance, ok := s.registry.Instances[instanceName]
+if !ok {
+return fmt.Errorf("instance %q is not registered", instanceName)
+}
+instance.Files[reference] = sha1
+return s.saveRegistry()
+}
+
+// installFromStore implements DownloadFileWithOptions when DownloadOptions.Store is set
+// The blob is fetched through the store, then linked or copied to destination
+// and recorded against DownloadOptions.StoreInstance
+func installFromStore(file File, destination string, options DownloadOptions) error {
+store := options.Store
+options.Store = nil
+
+// The final progress event is sent once the file is in place
+onProgress := options.OnProgress
+var progress *progressWriter
+if onProgress != nil {
+progress = newProgressWriter(file, onProgress, options.ProgressInterval, 0, file.FileLength)
+options.OnProgress = func(event DownloadProgress) {
+if !event.Done {
+onProgress(event)
+}
+}
+}
+
+err := placeStoredBlob(store, file, destination, options)
+if progress != nil {
+if err == nil {
+progress.resume(file.FileLength, file.FileLength)
+}
+progress.finish(err)
+}
+return err
+}
+
+// placeStoredBlob fetches file through store and places it at destination on options.FS
+func placeStoredBlob(store *BlobStore, file File, destination string, options DownloadOptions) error {
+if _, err := store.instanceDir(options.StoreInstance); err != nil {
+return fmt.Errorf("DownloadOptions.StoreInstance: %w", err)
+}
+
+fsys := orOSFS(options.FS)
+reference := destination
+place := func(blobPath string) error {
+return copyFileFS(OSFS{}, blobPath, fsys, destination)
+}
+if _, ok := fsys.(OSFS); ok {
+absDestination, err := filepath.Abs(destination)
+if err != nil {
+return err
+}
+reference = absDestination
+place = func(blobPath string) error {
+return linkOrCopyBlob(blobPath, destination)
+}
+}
+
+err := store.installBlob(options.StoreInstance, file, reference, options, place)
+if manual, ok := AsManualDownloadRequired(err); ok {
+manual.Destination = destination
+return manual
+}
+return err
+}
+
+// linkOrCopyBlob places a blob at destination by hardlink, or by copy if linking fails
+func linkOrCopyBlob(blobPath string, destination string) error {
+if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+return err
+}
+
+tmpPath := destination + DownloadPartSuffix
+os.Remove(tmpPath)
+if err := os.Link(blobPath, tmpPath); err != nil {
+contextLogger.Trace(fmt.Sprintf("hardlink failed, copying %s: %v", destination, err))
+return copyFileFS(OSFS{}, blobPath, OSFS{}, destination)
+}
+return os.Rename(tmpPath, destination)
+}
+
+// GarbageCollect drops references to installed files that no longer exist and
+// removes every blob not referenced by a registered instance
+// It returns the SHA1s of the removed blobs
+func (s *BlobStore) GarbageCollect() ([]string, error) {
+s.mu.Lock()
+defer s.mu.Unlock()
+
+referenced := make(map[string]bool)
+for _, instance := range s.registry.Instances {
+for destination, sha1 := range instance.Files {
+if _, err := os.Stat(destination); err != nil {
+delete(instance.Files, destination)
+continue
+}
+referenced[sha1] = true
+}
+}
+if err := s.saveRegistry(); err != nil {
+return nil, err
+}
+
+var removed []string
+err := filepath.WalkDir(filepath.Join(s.root, blobStoreBlobsDir), func(path string, entry fs.DirEntry, err error) error {
+if err != nil || entry.IsDir() {
+return err
+}
+
+sha1 := entry.Name()
+if referenced[sha1] {
+return nil
+}
+
+// Skip blobs being fetched or installed right now; new work on a blob
+// waits for s.mu, held until the walk is done
+if _, ok := s.fetching[sha1]; ok {
+return nil
+}
+if err := os.Remove(path); err != nil {
+return err
+}
+removed = append(removed, sha1)
+return nil
+})
+if err != nil {
+return removed, fmt.Errorf("failed to collect blobs: %w", err)
+}
+return removed, nil
+}
+
+// saveRegistry atomically writes the instance registry; callers hold s.mu
+func (s *BlobStore) saveRegistry() error {
+data, err := json.MarshalIndent(s.registry, "", "  ")
+if err != nil {
+return fmt.Errorf("failed to marshal blob store registry: %w", err)
+}
+
+tmp, err := os.CreateTemp(s.root, blobStoreRegistry+".*.tmp")
+if err != nil {
+return err
+}
+if _, err := tmp.Write(data); err != nil {
+tmp.Close()
+os.Remove(tmp.Name())
+return err
+}
+if err := tmp.Close(); err != nil {
+os.Remove(tmp.Name())
+return err
+}
+return os.Rename(tmp.Name(), filepath.Join(s.root, blobStoreRegistry))
+}
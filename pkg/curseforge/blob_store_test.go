@@ -0,0 +1,203 @@
+package curseforge
+
+import (
+"bytes"
+"fmt"
+"os"
+"path/filepath"
+"sync"
+"testing"
+)
+
+func TestBlobStore(t *testing.T) {
+content := fingerprintTestData(20000)
+server := newTestDownloadServer(t, content)
+file := testDownloadFile(server.URL, content)
+sha1 := GetSha1Hash(file)
+
+root := t.TempDir()
+store, err := OpenBlobStore(filepath.Join(root, "store"))
+if err != nil {
+t.Fatalf("OpenBlobStore failed: %v", err)
+}
+
+instances := map[string]string{"survival": filepath.Join(root, "survival"), "creative": filepath.Join(root, "creative")}
+for name, dir := range instances {
+if err := store.RegisterInstance(name, dir); err != nil {
+t.Fatalf("RegisterInstance failed: %v", err)
+}
+if err := store.InstallFile(name, file, filepath.Join("mods", "mod.jar"), DownloadOptions{}); err != nil {
+t.Fatalf("InstallFile failed: %v", err)
+}
+data, err := os.ReadFile(filepath.Join(dir, "mods", "mod.jar"))
+if err != nil || !bytes.Equal(data, content) {
+t.Fatalf("%s: installed content differs (err=%v)", name, err)
+}
+}
+
+if server.requests.Load() != 1 {
+t.Errorf("expected a single download for both instances, got %d", server.requests.Load())
+}
+if !store.Has(sha1) {
+t.Fatal("blob missing after install")
+}
+
+// Reopening keeps the registry
+store, err = OpenBlobStore(filepath.Join(root, "store"))
+if err != nil {
+t.Fatalf("OpenBlobStore failed: %v", err)
+}
+if names := store.Instances(); len(names) != 2 {
+t.Fatalf("Instances = %q", names)
+}
+
+if err := store.UnregisterInstance("survival"); err != nil {
+t.Fatal(err)
+}
+if removed, err := store.GarbageCollect(); err != nil || len(removed) != 0 {
+t.Fatalf("GarbageCollect removed %q (err=%v) while still referenced", removed, err)
+}
+
+// Deleting the file from the last instance releases the blob
+os.Remove(filepath.Join(instances["creative"], "mods", "mod.jar"))
+removed, err := store.GarbageCollect()
+if err != nil || len(removed) != 1 || removed[0] != sha1 {
+t.Fatalf("GarbageCollect removed %q (err=%v), want %s", removed, err, sha1)
+}
+if store.Has(sha1) {
+t.Error("blob still stored after garbage collection")
+}
+}
+
+func TestBlobStoreAdd(t *testing.T) {
+root := t.TempDir()
+store, err := OpenBlobStore(filepath.Join(root, "store"))
+if err != nil {
+t.Fatalf("OpenBlobStore failed: %v", err)
+}
+
+content := fingerprintTestData(1000)
+path := filepath.Join(root, "local.jar")
+os.WriteFile(path, content, 0o644)
+
+sha1, err := store.Add(path)
+if err != nil {
+t.Fatalf("Add failed: %v", err)
+}
+data, err := os.ReadFile(store.BlobPath(sha1))
+if err != nil || !bytes.Equal(data, content) {
+t.Errorf("stored blob differs (err=%v)", err)
+}
+
+// A file whose SHA1 is already stored is served without downloading
+file := File{ID: 1, FileName: "local.jar", DownloadURL: "http://127.0.0.1:1/unreachable", Hashes: []FileHash{{Value: sha1, Algo: HashAlgoSha1}}}
+if blobPath, err := store.Fetch(file, DownloadOptions{}); err != nil || blobPath != store.BlobPath(sha1) {
+t.Errorf("Fetch = %s, %v", blobPath, err)
+}
+}
+
+func TestDownloadThroughBlobStore(t *testing.T) {
+content := fingerprintTestData(20000)
+server := newTestDownloadServer(t, content)
+file := testDownloadFile(server.URL, content)
+
+root := t.TempDir()
+store, err := OpenBlobStore(filepath.Join(root, "store"))
+if err != nil {
+t.Fatalf("OpenBlobStore failed: %v", err)
+}
+
+if err := DownloadFileWithOptions(file, filepath.Join(root, "a", "mod.jar"), DownloadOptions{Store: store}); err == nil {
+t.Error("expected an error without a registered StoreInstance")
+}
+if err := store.RegisterInstance("main", root); err != nil {
+t.Fatal(err)
+}
+
+var done []DownloadProgress
+options := DownloadOptions{Store: store, StoreInstance: "main", OnProgress: func(p DownloadProgress) {
+if p.Done {
+done = append(done, p)
+}
+}}
+if err := DownloadFileWithOptions(file, filepath.Join(root, "a", "mod.jar"), options); err != nil {
+t.Fatalf("DownloadFileWithOptions failed: %v", err)
+}
+
+memFS := NewMemFS()
+summary := DownloadFiles([]DownloadRequest{
+{File: file, Destination: "b/mod.jar"},
+}, DownloadManagerOptions{Download: DownloadOptions{Store: store, StoreInstance: "main", FS: memFS}})
+if err := summary.Err(); err != nil {
+t.Fatalf("DownloadFiles failed: %v", err)
+}
+
+if server.requests.Load() != 1 {
+t.Errorf("expected one download through the store, got %d", server.requests.Load())
+}
+if data, err := os.ReadFile(filepath.Join(root, "a", "mod.jar")); err != nil || !bytes.Equal(data, content) {
+t.Errorf("installed content differs (err=%v)", err)
+}
+if data, err := memFS.ReadFile("b/mod.jar"); err != nil || !bytes.Equal(data, content) {
+t.Errorf("MemFS content differs (err=%v)", err)
+}
+if !store.Has(GetSha1Hash(file)) {
+t.Error("blob not stored")
+}
+if len(done) != 1 || done[0].Err != nil || done[0].BytesDone != int64(len(content)) {
+t.Errorf("Done events = %+v, want one successful event", done)
+}
+if len(store.fetching) != 0 {
+t.Errorf("%d blob locks left behind", len(store.fetching))
+}
+
+// The installed file keeps its blob alive
+if removed, err := store.GarbageCollect(); err != nil || len(removed) != 0 {
+t.Errorf("GarbageCollect removed %v (err=%v)", removed, err)
+}
+os.Remove(filepath.Join(root, "a", "mod.jar"))
+if removed, err := store.GarbageCollect(); err != nil || len(removed) != 1 {
+t.Errorf("GarbageCollect removed %v after the file was deleted (err=%v)", removed, err)
+}
+}
+
+func TestBlobStoreConcurrentInstall(t *testing.T) {
+content := fingerprintTestData(5000)
+server := newTestDownloadServer(t, content)
+file := testDownloadFile(server.URL, content)
+
+root := t.TempDir()
+store, err := OpenBlobStore(filepath.Join(root, "store"))
+if err != nil {
+t.Fatalf("OpenBlobStore failed: %v", err)
+}
+if err := store.RegisterInstance("main", filepath.Join(root, "main")); err != nil {
+t.Fatal(err)
+}
+
+var wg sync.WaitGroup
+for i := 0; i < 8; i++ {
+wg.Add(2)
+go func(i int) {
+defer wg.Done()
+destination := filepath.Join("mods", fmt.Sprintf("mod-%d.jar", i))
+if err := store.InstallFile("main", file, destination, DownloadOptions{}); err != nil {
+t.Errorf("InstallFile failed: %v", err)
+}
+}(i)
+go func() {
+defer wg.Done()
+if err := store.RegisterInstance("main", filepath.Join(root, "main")); err != nil {
+t.Errorf("RegisterInstance failed: %v", err)
+}
+}()
+}
+wg.Wait()
+
+if server.requests.Load() != 1 {
+t.Errorf("expected a single download, got %d", server.requests.Load())
+}
+if len(store.fetching) != 0 {
+t.Errorf("%d blob locks left behind", len(store.fetching))
+}
+}
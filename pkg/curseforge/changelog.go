@@ -0,0 +1,195 @@
+package curseforge
+
+import (
+"errors"
+"fmt"
+"sort"
+"strings"
+"sync"
+)
+
+// DefaultChangelogWorkers is the default number of concurrent changelog requests
+const DefaultChangelogWorkers = 4
+
+// ChangelogOptions configures CollectChangelogs
+type ChangelogOptions struct {
+Workers int // concurrent changelog requests, defaults to DefaultChangelogWorkers
+
+// IncludeCurrent also fetches the installed file's own changelog
+IncludeCurrent bool
+}
+
+// ChangelogEntry is the changelog of one file
+// Changelog is the HTML returned by GetModFileChangelog
+type ChangelogEntry struct {
+File      File
+Changelog string
+Err       error
+}
+
+// ChangelogHistory is the result of CollectChangelogs
+type ChangelogHistory struct {
+Current File
+Target  File
+Entries []ChangelogEntry // oldest first, ending with Target
+}
+
+// Err returns the changelog requests that failed joined into one error, or nil
+func (h *ChangelogHistory) Err() error {
+var errs []error
+for _, entry := range h.Entries {
+if entry.Err != nil {
+errs = append(errs, fmt.Errorf("file %d: %w", entry.File.ID, entry.Err))
+}
+}
+return errors.Join(errs...)
+}
+
+// Markdown renders the history as one Markdown document, newest first
+// Each file gets a heading with its display name, release type and date, and
+// its changelog is converted with HTMLToMarkdown
+func (h *ChangelogHistory) Markdown() string {
+var b strings.Builder
+for i := len(h.Entries) - 1; i >= 0; i-- {
+entry := h.Entries[i]
+name := entry.File.DisplayName
+if name == "" {
+name = entry.File.FileName
+}
+fmt.Fprintf(&b, "## %s\n\n", name)
+fmt.Fprintf(&b, "*%s, %s*\n\n", entry.File.ReleaseType, entry.File.FileDate.Format("2006-01-02"))
+
+switch {
+case entry.Err != nil:
+b.WriteString("_Changelog unavailable._\n\n")
+case strings.TrimSpace(entry.Changelog) == "":
+b.WriteString("_No changelog._\n\n")
+default:
+changelog, err := HTMLToMarkdown(entry.Changelog)
+if err != nil {
+changelog = strings.TrimSpace(entry.Changelog)
+}
+b.WriteString(changelog)
+b.WriteString("\n\n")
+}
+}
+return strings.TrimSuffix(b.String(), "\n")
+}
+
+// CollectChangelogs gathers the changelogs of every file between an installed
+// file and the file it is being updated to
+//
+// The mod's files are listed with GetAllModFiles and those published after
+// current and up to target on the same line (sharing a Minecraft version and
+// loader with target, server packs excluded) are kept. Their changelogs are
+// fetched concurrently; failures are recorded on the entry, see Err.
+func CollectChangelogs(server CurseForgeServer, current File, target File, options ChangelogOptions) (*ChangelogHistory, error) {
+request := &GetModFilesRequest{}
+if loaders := target.Loaders(); len(loaders) == 1 {
+request.ModLoaderType = loaders[0]
+}
+files, err := GetAllModFiles(server, target.ModID, request)
+if err != nil {
+return nil, fmt.Errorf("failed to list files of mod %d: %w", target.ModID, err)
+}
+
+between := filesBetween(files, current, target, options.IncludeCurrent)
+contextLogger.Trace(fmt.Sprintf("Fetching %d changelogs for mod %d", len(between), target.ModID))
+
+history := &ChangelogHistory{Current: current, Target: target}
+history.Entries = fetchChangelogs(between, options.Workers, func(file File) (string, error) {
+return GetModFileChangelog(server, file.ModID, file.ID)
+})
+return history, nil
+}
+
+// CollectUpdateChangelogs gathers the changelogs for an update found by CheckUpdates
+func CollectUpdateChangelogs(server CurseForgeServer, update FileUpdate, options ChangelogOptions) (*ChangelogHistory, error) {
+return CollectChangelogs(server, update.Current, update.Latest, options)
+}
+
+// filesBetween selects the files on target's line published after current up to target
+// The result is sorted by FileDate and always ends with target
+func filesBetween(files []File, current File, target File, includeCurrent bool) []File {
+versions := make(map[string]bool)
+for _, version := range target.MinecraftVersions() {
+versions[strings.ToLower(version.Raw)] = true
+}
+loaders := target.Loaders()
+
+onLine := func(file File) bool {
+if file.IsServerPack != nil && *file.IsServerPack {
+return false
+}
+for _, loader := range loaders {
+if !file.HasModLoader(loader) {
+return false
+}
+}
+if len(versions) == 0 {
+return true
+}
+for _, version := range file.MinecraftVersions() {
+if versions[strings.ToLower(version.Raw)] {
+return true
+}
+}
+return false
+}
+
+var between []File
+seen := map[int]bool{target.ID: true}
+if includeCurrent {
+between = append(between, current)
+seen[current.ID] = true
+}
+for _, file := range files {
+if seen[file.ID] || file.ID == current.ID {
+continue
+}
+if !file.FileDate.After(current.FileDate) || file.FileDate.After(target.FileDate) {
+continue
+}
+if onLine(file) {
+seen[file.ID] = true
+between = append(between, file)
+}
+}
+
+sort.SliceStable(between, func(i, j int) bool {
+if !between[i].FileDate.Equal(between[j].FileDate) {
+return between[i].FileDate.Before(between[j].FileDate)
+}
+return between[i].ID < between[j].ID
+})
+return append(between, target)
+}
+
+// fetchChangelogs fetches changelogs with a pool of workers, keeping the file order
+func fetchChangelogs(files []File, workers int, fetch func(file File) (string, error)) []ChangelogEntry {
+if workers <= 0 {
+workers = DefaultChangelogWorkers
+}
+entries := make([]ChangelogEntry, len(files))
+jobs := make(chan int)
+
+var wg sync.WaitGroup
+for i := 0; i < workers; i++ {
+wg.Add(1)
+go func() {
+defer wg.Done()
+for idx := range jobs {
+changelog, err := fetch(files[idx])
+entries[idx] = ChangelogEntry{File: files[idx], Changelog: changelog, Err: err}
+}
+}()
+}
+
+for idx := range files {
+jobs <- idx
+}
+close(jobs)
+wg.Wait()
+
+return entries
+}
@@ -0,0 +1,86 @@
+package curseforge
+
+import (
+"encoding/json"
+"fmt"
+"net/http"
+"net/http/httptest"
+"strings"
+"testing"
+)
+
+func TestCollectChangelogs(t *testing.T) {
+forge := []string{"1.20.1", "Forge"}
+current := selectorTestFile(1, 1, ReleaseTypeRelease, forge...)
+target := selectorTestFile(5, 9, ReleaseTypeRelease, forge...)
+files := []File{
+current,
+selectorTestFile(2, 3, ReleaseTypeBeta, forge...),
+selectorTestFile(3, 4, ReleaseTypeRelease, "1.19.2", "Forge"),  // other version line
+selectorTestFile(4, 5, ReleaseTypeRelease, "1.20.1", "Fabric"), // other loader
+selectorTestFile(6, 6, ReleaseTypeRelease, forge...),
+target,
+selectorTestFile(7, 12, ReleaseTypeRelease, forge...), // newer than target
+}
+
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch {
+case r.URL.Path == "/v1/mods/1/files":
+json.NewEncoder(w).Encode(PaginatedResponse[[]File]{Data: files, Pagination: Pagination{ResultCount: len(files), TotalCount: len(files)}})
+case r.URL.Path == "/v1/mods/1/files/6/changelog":
+w.WriteHeader(http.StatusInternalServerError)
+case strings.HasSuffix(r.URL.Path, "/changelog"):
+id := strings.Split(r.URL.Path, "/")[5]
+json.NewEncoder(w).Encode(Response[string]{Data: fmt.Sprintf("<p>Changes in %s</p>", id)})
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+defer server.Close()
+
+history, err := CollectChangelogs(NewServerWithURL("key", server.URL), current, target, ChangelogOptions{Workers: 2})
+if err != nil {
+t.Fatalf("CollectChangelogs failed: %v", err)
+}
+
+var ids []int
+for _, entry := range history.Entries {
+ids = append(ids, entry.File.ID)
+}
+if fmt.Sprint(ids) != "[2 6 5]" {
+t.Fatalf("entries = %v, want [2 6 5] ordered by date", ids)
+}
+if history.Entries[0].Changelog != "<p>Changes in 2</p>" {
+t.Errorf("changelog = %q", history.Entries[0].Changelog)
+}
+if history.Entries[1].Err == nil || history.Err() == nil {
+t.Error("expected the failed changelog to be reported")
+}
+
+markdown := history.Markdown()
+if strings.Index(markdown, "Changes in 5") > strings.Index(markdown, "Changes in 2") {
+t.Errorf("Markdown should list the newest file first:\n%s", markdown)
+}
+if strings.Contains(markdown, "<p>") {
+t.Errorf("Markdown should convert changelog HTML:\n%s", markdown)
+}
+if !strings.Contains(markdown, "_Changelog unavailable._") || !strings.Contains(markdown, "*beta, 2023-06-03*") {
+t.Errorf("unexpected Markdown:\n%s", markdown)
+}
+}
+
+func TestFilesBetweenIncludeCurrent(t *testing.T) {
+current := selectorTestFile(1, 1, ReleaseTypeRelease, "1.20.1")
+target := selectorTestFile(2, 2, ReleaseTypeRelease, "1.20.1")
+
+between := filesBetween([]File{current, target}, current, target, true)
+if len(between) != 2 || between[0].ID != 1 || between[1].ID != 2 {
+t.Errorf("filesBetween() = %+v, want current then target", between)
+}
+
+between = filesBetween(nil, current, target, false)
+if len(between) != 1 || between[0].ID != 2 {
+t.Errorf("filesBetween() = %+v, want target only", between)
+}
+}
@@ -1,6 +1,7 @@
 package curseforge
 
 import (
+"strings"
 "time"
 )
 
@@ -66,19 +67,22 @@ return "Any"
 }
 
 // ModLoaderFromString converts a string to ModLoaderType
+// Matching ignores case, spaces, dashes and underscores and accepts common
+// aliases such as "MinecraftForge", "NeoForged" and "FabricMC"
 func ModLoaderFromString(loader string) ModLoaderType {
-switch loader {
-case "forge", "Forge":
+normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(loader)))
+switch normalized {
+case "forge", "minecraftforge":
 return ModLoaderForge
-case "fabric", "Fabric":
+case "fabric", "fabricmc", "fabricloader":
 return ModLoaderFabric
-case "quilt", "Quilt":
+case "quilt", "quiltmc", "quiltloader":
 return ModLoaderQuilt
-case "neoforge", "NeoForge":
+case "neoforge", "neoforged":
 return ModLoaderNeoForge
-case "liteloader", "LiteLoader":
+case "liteloader":
 return ModLoaderLiteLoader
-case "cauldron", "Cauldron":
+case "cauldron":
 return ModLoaderCauldron
 default:
 return ModLoaderAny
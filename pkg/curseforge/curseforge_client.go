@@ -7,7 +7,6 @@ import (
 "io"
 "net/http"
 "net/url"
-"os"
 "strconv"
 
 log "github.com/sirupsen/logrus"
@@ -304,6 +303,33 @@ return nil, nil, err
 return response.Data, &response.Pagination, nil
 }
 
+// GetAllModFiles retrieves every page of files for a mod with optional filtering
+// request.Index is ignored; request.PageSize defaults to the API maximum of 50
+func GetAllModFiles(server CurseForgeServer, modID int, request *GetModFilesRequest) ([]File, error) {
+pageRequest := GetModFilesRequest{PageSize: 50}
+if request != nil {
+pageRequest = *request
+pageRequest.Index = 0
+if pageRequest.PageSize == 0 {
+pageRequest.PageSize = 50
+}
+}
+
+var files []File
+for {
+page, pagination, err := GetModFiles(server, modID, &pageRequest)
+if err != nil {
+return nil, err
+}
+files = append(files, page...)
+
+pageRequest.Index += len(page)
+if len(page) == 0 || pageRequest.Index >= pagination.TotalCount {
+return files, nil
+}
+}
+}
+
 // GetFiles retrieves multiple files by their IDs
 func GetFiles(server CurseForgeServer, fileIDs []int) ([]File, error) {
 var response Response[[]File]
@@ -450,29 +476,11 @@ return &response.Data, nil
 // ============================================================================
 
 // DownloadFile downloads a mod file to the specified destination
+// The download is verified against File.FileLength and File.Hashes, written
+// atomically and resumed if a previous attempt was interrupted
+// See DownloadFileWithOptions for details
 func DownloadFile(file File, destination string) error {
-if file.DownloadURL == "" {
-return fmt.Errorf("download URL is not available for this file")
-}
-
-out, err := os.Create(destination)
-if err != nil {
-return err
-}
-defer out.Close()
-
-resp, err := http.Get(file.DownloadURL)
-if err != nil {
-return err
-}
-defer resp.Body.Close()
-
-if resp.StatusCode != http.StatusOK {
-return fmt.Errorf("download failed with status: %d", resp.StatusCode)
-}
-
-_, err = io.Copy(out, resp.Body)
-return err
+return DownloadFileWithOptions(file, destination, DownloadOptions{})
 }
 
 // ============================================================================
@@ -510,13 +518,7 @@ return false
 }
 
 // HasModLoader checks if a file supports a specific mod loader
+// Loader names are matched case-insensitively, including aliases, see Loaders
 func (f File) HasModLoader(loader ModLoaderType) bool {
-// Check GameVersions for loader name
-loaderName := loader.String()
-for _, v := range f.GameVersions {
-if v == loaderName {
-return true
-}
-}
-return false
+return containsLoader(f.Loaders(), loader)
 }
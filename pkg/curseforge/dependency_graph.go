@@ -0,0 +1,159 @@
+package curseforge
+
+import (
+"fmt"
+"sort"
+"strconv"
+"strings"
+)
+
+// DependencyGraphNode is a mod in a DependencyGraph
+type DependencyGraphNode struct {
+ModID     int
+Label     string // Mod.Name, falling back to the file's display name or "mod <id>"
+Installed bool   // false for mods only referenced as a dependency
+}
+
+// DependencyGraphEdge is a declared dependency from one mod on another
+type DependencyGraphEdge struct {
+From     int
+To       int
+Relation DependencyType
+}
+
+// DependencyGraph is the dependency graph of a mod set
+// Nodes are sorted by mod ID and edges by source, target and relation
+type DependencyGraph struct {
+Nodes []DependencyGraphNode
+Edges []DependencyGraphEdge
+}
+
+// dependencyEdgeStyle is how a relation type is drawn
+type dependencyEdgeStyle struct {
+dot     string // Graphviz edge attributes
+mermaid string // Mermaid link operator
+color   string
+}
+
+var dependencyEdgeStyles = map[DependencyType]dependencyEdgeStyle{
+DependencyTypeRequired:        {dot: "style=solid", mermaid: "-->", color: "black"},
+DependencyTypeOptional:        {dot: "style=dashed", mermaid: "-.->", color: "gray"},
+DependencyTypeEmbeddedLibrary: {dot: "style=bold, arrowhead=diamond", mermaid: "==>", color: "blue"},
+DependencyTypeIncompatible:    {dot: "style=solid, arrowhead=tee", mermaid: "--x", color: "red"},
+DependencyTypeTool:            {dot: "style=dotted, arrowhead=empty", mermaid: "-.->", color: "darkgreen"},
+DependencyTypeInclude:         {dot: "style=solid, arrowhead=odiamond", mermaid: "--o", color: "purple"},
+}
+
+// edgeStyle returns the style for a relation, treating unknown types as required
+func edgeStyle(relation DependencyType) dependencyEdgeStyle {
+if style, ok := dependencyEdgeStyles[relation]; ok {
+return style
+}
+return dependencyEdgeStyles[DependencyTypeRequired]
+}
+
+// NewDependencyGraph builds the graph of the dependencies declared by files
+// mods supplies node labels and may be nil; mods referenced by a dependency
+// but not in files are added as nodes with Installed set to false
+func NewDependencyGraph(files []File, mods []Mod) *DependencyGraph {
+names := make(map[int]string)
+for _, mod := range mods {
+names[mod.ID] = mod.Name
+}
+
+installed := make(map[int]string) // mod ID -> file display name
+referenced := make(map[int]bool)
+seen := make(map[DependencyGraphEdge]bool)
+graph := &DependencyGraph{}
+for _, file := range files {
+installed[file.ModID] = file.DisplayName
+referenced[file.ModID] = true
+for _, dependency := range file.Dependencies {
+referenced[dependency.ModID] = true
+edge := DependencyGraphEdge{From: file.ModID, To: dependency.ModID, Relation: dependency.RelationType}
+if !seen[edge] {
+seen[edge] = true
+graph.Edges = append(graph.Edges, edge)
+}
+}
+}
+
+for modID := range referenced {
+displayName, isInstalled := installed[modID]
+label := names[modID]
+if label == "" {
+label = displayName
+}
+if label == "" {
+label = fmt.Sprintf("mod %d", modID)
+}
+graph.Nodes = append(graph.Nodes, DependencyGraphNode{ModID: modID, Label: label, Installed: isInstalled})
+}
+sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ModID < graph.Nodes[j].ModID })
+sort.Slice(graph.Edges, func(i, j int) bool {
+a, b := graph.Edges[i], graph.Edges[j]
+if a.From != b.From {
+return a.From < b.From
+}
+if a.To != b.To {
+return a.To < b.To
+}
+return a.Relation < b.Relation
+})
+return graph
+}
+
+// DOT renders the graph in Graphviz DOT format
+// Mods that are not installed are drawn dashed
+func (g *DependencyGraph) DOT() string {
+var b strings.Builder
+b.WriteString("digraph dependencies {\n")
+b.WriteString("  rankdir=LR;\n")
+b.WriteString("  node [shape=box];\n")
+for _, node := range g.Nodes {
+attributes := "label=" + strconv.Quote(node.Label)
+if !node.Installed {
+attributes += ", style=dashed"
+}
+fmt.Fprintf(&b, "  m%d [%s];\n", node.ModID, attributes)
+}
+for _, edge := range g.Edges {
+style := edgeStyle(edge.Relation)
+fmt.Fprintf(&b, "  m%d -> m%d [%s, color=%s, tooltip=%q];\n", edge.From, edge.To, style.dot, style.color, edge.Relation.String())
+}
+b.WriteString("}\n")
+return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+// Mods that are not installed use the "external" class
+func (g *DependencyGraph) Mermaid() string {
+var b strings.Builder
+b.WriteString("flowchart LR\n")
+
+var external []string
+for _, node := range g.Nodes {
+fmt.Fprintf(&b, "  m%d[\"%s\"]\n", node.ModID, mermaidEscape(node.Label))
+if !node.Installed {
+external = append(external, fmt.Sprintf("m%d", node.ModID))
+}
+}
+for i, edge := range g.Edges {
+style := edgeStyle(edge.Relation)
+fmt.Fprintf(&b, "  m%d %s|%s| m%d\n", edge.From, style.mermaid, edge.Relation.String(), edge.To)
+if style.color != "black" {
+fmt.Fprintf(&b, "  linkStyle %d stroke:%s\n", i, style.color)
+}
+}
+
+if len(external) > 0 {
+b.WriteString("  classDef external stroke-dasharray: 5 5\n")
+fmt.Fprintf(&b, "  class %s external\n", strings.Join(external, ","))
+}
+return b.String()
+}
+
+// mermaidEscape makes a label safe inside a quoted Mermaid node label
+func mermaidEscape(label string) string {
+return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(label)
+}
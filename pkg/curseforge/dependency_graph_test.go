@@ -0,0 +1,98 @@
+package curseforge
+
+import (
+"strings"
+"testing"
+)
+
+func testDependencyGraph() *DependencyGraph {
+files := []File{
+{ModID: 2, DisplayName: "Create 0.5.1", Dependencies: []FileDependency{
+{ModID: 1, RelationType: DependencyTypeRequired},
+{ModID: 3, RelationType: DependencyTypeOptional},
+{ModID: 1, RelationType: DependencyTypeRequired},
+}},
+{ModID: 1, DisplayName: "flywheel.jar", Dependencies: []FileDependency{
+{ModID: 4, RelationType: DependencyTypeIncompatible},
+}},
+}
+mods := []Mod{{ID: 2, Name: `Create "Mod"`}}
+return NewDependencyGraph(files, mods)
+}
+
+func TestNewDependencyGraph(t *testing.T) {
+graph := testDependencyGraph()
+
+expectedNodes := []DependencyGraphNode{
+{ModID: 1, Label: "flywheel.jar", Installed: true},
+{ModID: 2, Label: `Create "Mod"`, Installed: true},
+{ModID: 3, Label: "mod 3"},
+{ModID: 4, Label: "mod 4"},
+}
+if len(graph.Nodes) != len(expectedNodes) {
+t.Fatalf("Nodes = %+v", graph.Nodes)
+}
+for i, node := range expectedNodes {
+if graph.Nodes[i] != node {
+t.Errorf("Nodes[%d] = %+v, want %+v", i, graph.Nodes[i], node)
+}
+}
+
+expectedEdges := []DependencyGraphEdge{
+{From: 1, To: 4, Relation: DependencyTypeIncompatible},
+{From: 2, To: 1, Relation: DependencyTypeRequired},
+{From: 2, To: 3, Relation: DependencyTypeOptional},
+}
+if len(graph.Edges) != len(expectedEdges) {
+t.Fatalf("Edges = %+v, want duplicates removed", graph.Edges)
+}
+for i, edge := range expectedEdges {
+if graph.Edges[i] != edge {
+t.Errorf("Edges[%d] = %+v, want %+v", i, graph.Edges[i], edge)
+}
+}
+}
+
+func TestDependencyGraphRender(t *testing.T) {
+graph := testDependencyGraph()
+
+tests := []struct {
+name     string
+output   string
+contains []string
+}{
+{
+name:   "dot",
+output: graph.DOT(),
+contains: []string{
+"digraph dependencies {\n",
+`  m2 [label="Create \"Mod\""];`,
+`  m3 [label="mod 3", style=dashed];`,
+`  m2 -> m1 [style=solid, color=black, tooltip="required"];`,
+`  m2 -> m3 [style=dashed, color=gray, tooltip="optional"];`,
+`  m1 -> m4 [style=solid, arrowhead=tee, color=red, tooltip="incompatible"];`,
+},
+},
+{
+name:   "mermaid",
+output: graph.Mermaid(),
+contains: []string{
+"flowchart LR\n",
+`  m2["Create #quot;Mod#quot;"]`,
+"  m1 --x|incompatible| m4\n  linkStyle 0 stroke:red\n",
+"  m2 -->|required| m1\n  m2 -.->|optional| m3\n  linkStyle 2 stroke:gray\n",
+"  class m3,m4 external\n",
+},
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+for _, expected := range tt.contains {
+if !strings.Contains(tt.output, expected) {
+t.Errorf("output missing %q:\n%s", expected, tt.output)
+}
+}
+})
+}
+}
@@ -0,0 +1,237 @@
+package curseforge
+
+import "fmt"
+
+// ResolveOptions configures ResolveDependencies
+// Files are picked with SelectFileFrom; server packs are always excluded
+type ResolveOptions struct {
+FileConstraints
+
+// IncludeOptional also resolves optional dependencies
+IncludeOptional bool
+}
+
+// ResolvedFile is a file selected for a mod in the resolved set
+type ResolvedFile struct {
+File       File
+RequiredBy []int // mod IDs depending on this mod, empty for roots
+IsRoot     bool
+}
+
+// UnresolvedReason explains why a dependency could not be resolved
+type UnresolvedReason string
+
+const (
+UnresolvedLookupFailed     UnresolvedReason = "lookupFailed"
+UnresolvedNoCompatibleFile UnresolvedReason = "noCompatibleFile"
+UnresolvedIncompatible     UnresolvedReason = "incompatible"
+)
+
+// UnresolvedDependency describes a mod that could not be added to the set
+// A mod required by several mods is listed once per dependent
+type UnresolvedDependency struct {
+ModID      int
+RequiredBy int // 0 for root mods
+Reason     UnresolvedReason
+Detail     string
+}
+
+// DependencyResolution is the result of ResolveDependencies
+type DependencyResolution struct {
+// Files holds one file per resolved mod, dependencies before the mods
+// depending on them
+Files      []ResolvedFile
+Unresolved []UnresolvedDependency
+Cycles     [][]int // mod ID chains that depend on themselves, e.g. [A B A]
+}
+
+// FileIDs returns the IDs of the resolved files, e.g. for GetFiles
+func (r *DependencyResolution) FileIDs() []int {
+ids := make([]int, 0, len(r.Files))
+for _, resolved := range r.Files {
+ids = append(ids, resolved.File.ID)
+}
+return ids
+}
+
+// ResolveDependencies builds the installable file set for rootModIDs
+//
+// For every mod the best compatible file for the target game version and
+// loader is fetched via GetModFiles; its required (and optionally, optional)
+// dependencies are resolved recursively. Each mod appears once. Embedded
+// libraries ship inside their parent and tools are not installed, so neither
+// is followed. Dependency cycles are reported but do not fail the resolution;
+// mods that cannot be resolved, and resolved files declaring each other
+// incompatible, are listed in Unresolved. An error is only returned when a
+// root mod could not be looked up, alongside the partial resolution.
+func ResolveDependencies(server CurseForgeServer, rootModIDs []int, options ResolveOptions) (*DependencyResolution, error) {
+listFiles := func(modID int) ([]File, error) {
+request := &GetModFilesRequest{ModLoaderType: options.ModLoader}
+if len(options.FallbackGameVersions) == 0 && options.VersionConstraint == nil {
+request.GameVersion = options.GameVersion
+}
+return GetAllModFiles(server, modID, request)
+}
+resolution := resolveDependencies(rootModIDs, options, listFiles)
+for _, unresolved := range resolution.Unresolved {
+if unresolved.RequiredBy == 0 && unresolved.Reason == UnresolvedLookupFailed {
+return resolution, fmt.Errorf("failed to look up mod %d: %s", unresolved.ModID, unresolved.Detail)
+}
+}
+return resolution, nil
+}
+
+// dependencyResolver holds the state of a single resolution
+type dependencyResolver struct {
+options   ResolveOptions
+listFiles func(modID int) ([]File, error)
+
+result   DependencyResolution
+resolved map[int]int // mod ID -> index in result.Files
+visiting map[int]bool
+failed   map[int]UnresolvedDependency // first failure of each mod
+stack    []int
+
+// cycleRequiredBy holds the dependents found through a cycle while the mod
+// was still being visited; they are added once it is resolved
+cycleRequiredBy map[int][]int
+}
+
+// resolveDependencies resolves the roots using listFiles to look up candidate files
+func resolveDependencies(rootModIDs []int, options ResolveOptions, listFiles func(modID int) ([]File, error)) *DependencyResolution {
+options.ExcludeServerPacks = true
+r := &dependencyResolver{
+options:   options,
+listFiles: listFiles,
+resolved:  make(map[int]int),
+visiting:  make(map[int]bool),
+failed:    make(map[int]UnresolvedDependency),
+
+cycleRequiredBy: make(map[int][]int),
+}
+
+for _, modID := range rootModIDs {
+r.visit(modID, 0)
+if idx, ok := r.resolved[modID]; ok {
+r.result.Files[idx].IsRoot = true
+}
+}
+
+r.checkIncompatibilities()
+return &r.result
+}
+
+// visit resolves a mod and its dependencies depth-first
+func (r *dependencyResolver) visit(modID int, requiredBy int) {
+if r.visiting[modID] {
+cycle := []int{modID}
+for i := len(r.stack) - 1; i >= 0; i-- {
+cycle = append([]int{r.stack[i]}, cycle...)
+if r.stack[i] == modID {
+break
+}
+}
+r.result.Cycles = append(r.result.Cycles, cycle)
+r.cycleRequiredBy[modID] = append(r.cycleRequiredBy[modID], requiredBy)
+return
+}
+if idx, ok := r.resolved[modID]; ok {
+r.addRequiredBy(idx, requiredBy)
+return
+}
+if failure, ok := r.failed[modID]; ok {
+r.addUnresolvedRequiredBy(failure, requiredBy)
+return
+}
+
+files, err := r.listFiles(modID)
+if err != nil {
+r.fail(modID, requiredBy, UnresolvedLookupFailed, err.Error())
+return
+}
+selection := SelectFileFrom(files, r.options.FileConstraints)
+if !selection.Found() {
+r.fail(modID, requiredBy, UnresolvedNoCompatibleFile, selection.Explain())
+return
+}
+best := selection.File
+
+r.visiting[modID] = true
+r.stack = append(r.stack, modID)
+for _, dependency := range best.Dependencies {
+switch dependency.RelationType {
+case DependencyTypeRequired:
+r.visit(dependency.ModID, modID)
+case DependencyTypeOptional:
+if r.options.IncludeOptional {
+r.visit(dependency.ModID, modID)
+}
+}
+}
+r.stack = r.stack[:len(r.stack)-1]
+delete(r.visiting, modID)
+
+// Post-order: every dependency is already in Files
+r.resolved[modID] = len(r.result.Files)
+r.result.Files = append(r.result.Files, ResolvedFile{File: *best})
+r.addRequiredBy(r.resolved[modID], requiredBy)
+for _, dependent := range r.cycleRequiredBy[modID] {
+r.addRequiredBy(r.resolved[modID], dependent)
+}
+delete(r.cycleRequiredBy, modID)
+}
+
+// addRequiredBy records a dependent mod on a resolved file
+func (r *dependencyResolver) addRequiredBy(idx int, requiredBy int) {
+if requiredBy == 0 {
+return
+}
+for _, existing := range r.result.Files[idx].RequiredBy {
+if existing == requiredBy {
+return
+}
+}
+r.result.Files[idx].RequiredBy = append(r.result.Files[idx].RequiredBy, requiredBy)
+}
+
+// fail records an unresolved mod
+func (r *dependencyResolver) fail(modID int, requiredBy int, reason UnresolvedReason, detail string) {
+failure := UnresolvedDependency{
+ModID:      modID,
+RequiredBy: requiredBy,
+Reason:     reason,
+Detail:     detail,
+}
+r.failed[modID] = failure
+r.result.Unresolved = append(r.result.Unresolved, failure)
+}
+
+// addUnresolvedRequiredBy records another dependent of a mod that already failed
+func (r *dependencyResolver) addUnresolvedRequiredBy(failure UnresolvedDependency, requiredBy int) {
+for _, existing := range r.result.Unresolved {
+if existing.ModID == failure.ModID && existing.RequiredBy == requiredBy && existing.Reason == failure.Reason {
+return
+}
+}
+failure.RequiredBy = requiredBy
+r.result.Unresolved = append(r.result.Unresolved, failure)
+}
+
+// checkIncompatibilities reports resolved files declaring another resolved mod incompatible
+func (r *dependencyResolver) checkIncompatibilities() {
+for _, resolved := range r.result.Files {
+for _, dependency := range resolved.File.Dependencies {
+if dependency.RelationType != DependencyTypeIncompatible {
+continue
+}
+if _, ok := r.resolved[dependency.ModID]; ok {
+r.result.Unresolved = append(r.result.Unresolved, UnresolvedDependency{
+ModID:      dependency.ModID,
+RequiredBy: resolved.File.ModID,
+Reason:     UnresolvedIncompatible,
+Detail:     fmt.Sprintf("mod %d declares mod %d incompatible", resolved.File.ModID, dependency.ModID),
+})
+}
+}
+}
+}
@@ -0,0 +1,178 @@
+package curseforge
+
+import (
+"encoding/json"
+"errors"
+"fmt"
+"net/http"
+"net/http/httptest"
+"strconv"
+"strings"
+"testing"
+"time"
+)
+
+// resolverTestFile builds an available file for a mod with the given dependencies
+func resolverTestFile(fileID, modID int, day int, versions []string, dependencies ...FileDependency) File {
+return File{
+ID:           fileID,
+ModID:        modID,
+IsAvailable:  true,
+ReleaseType:  ReleaseTypeRelease,
+FileDate:     time.Date(2023, 6, day, 0, 0, 0, 0, time.UTC),
+GameVersions: versions,
+Dependencies: dependencies,
+}
+}
+
+func requiredDependency(modID int) FileDependency {
+return FileDependency{ModID: modID, RelationType: DependencyTypeRequired}
+}
+
+func TestResolveDependencies(t *testing.T) {
+forge := []string{"1.20.1", "Forge"}
+catalog := map[int][]File{
+// root: picks the newest 1.20.1 Forge file, which requires 2 and 3
+1: {
+resolverTestFile(10, 1, 1, forge, requiredDependency(2)),
+resolverTestFile(11, 1, 5, forge, requiredDependency(2), requiredDependency(3), FileDependency{ModID: 6, RelationType: DependencyTypeOptional}),
+resolverTestFile(12, 1, 9, []string{"1.19.2", "Forge"}),
+},
+2: {resolverTestFile(20, 2, 1, forge, requiredDependency(4), FileDependency{ModID: 7, RelationType: DependencyTypeEmbeddedLibrary})},
+3: {resolverTestFile(30, 3, 1, forge, requiredDependency(4), requiredDependency(5))},
+4: {resolverTestFile(40, 4, 1, forge)},
+5: {resolverTestFile(50, 5, 1, []string{"1.20.1", "Fabric"})},
+6: {resolverTestFile(60, 6, 1, forge)},
+}
+lookups := make(map[int]int)
+listFiles := func(modID int) ([]File, error) {
+lookups[modID]++
+return catalog[modID], nil
+}
+
+result := resolveDependencies([]int{1}, ResolveOptions{FileConstraints: FileConstraints{GameVersion: "1.20.1", ModLoader: ModLoaderForge}}, listFiles)
+
+if got := fmt.Sprint(result.FileIDs()); got != "[40 20 30 11]" {
+t.Errorf("FileIDs() = %s, want dependencies before dependents", got)
+}
+if !result.Files[3].IsRoot || result.Files[0].IsRoot {
+t.Errorf("only mod 1 should be a root: %+v", result.Files)
+}
+if got := fmt.Sprint(result.Files[0].RequiredBy); got != "[2 3]" {
+t.Errorf("mod 4 RequiredBy = %s, want [2 3]", got)
+}
+if lookups[4] != 1 {
+t.Errorf("shared dependency looked up %d times, want 1", lookups[4])
+}
+if lookups[6] != 0 || lookups[7] != 0 {
+t.Error("optional and embedded dependencies should not be resolved")
+}
+
+if len(result.Unresolved) != 1 {
+t.Fatalf("Unresolved = %+v, want mod 5", result.Unresolved)
+}
+if u := result.Unresolved[0]; u.ModID != 5 || u.RequiredBy != 3 || u.Reason != UnresolvedNoCompatibleFile {
+t.Errorf("Unresolved[0] = %+v", u)
+}
+
+withOptional := resolveDependencies([]int{1}, ResolveOptions{FileConstraints: FileConstraints{GameVersion: "1.20.1", ModLoader: ModLoaderForge}, IncludeOptional: true}, listFiles)
+if len(withOptional.Files) != 5 {
+t.Errorf("IncludeOptional resolved %d files, want 5", len(withOptional.Files))
+}
+}
+
+func TestResolveDependenciesCyclesAndConflicts(t *testing.T) {
+catalog := map[int][]File{
+1: {resolverTestFile(10, 1, 1, nil, requiredDependency(2))},
+2: {resolverTestFile(20, 2, 1, nil, requiredDependency(3))},
+3: {resolverTestFile(30, 3, 1, nil, requiredDependency(1), FileDependency{ModID: 4, RelationType: DependencyTypeIncompatible})},
+4: {resolverTestFile(40, 4, 1, nil)},
+}
+listFiles := func(modID int) ([]File, error) {
+if modID == 9 {
+return nil, errors.New("boom")
+}
+return catalog[modID], nil
+}
+
+result := resolveDependencies([]int{1, 4, 9}, ResolveOptions{}, listFiles)
+
+if got := fmt.Sprint(result.Cycles); got != "[[1 2 3 1]]" {
+t.Errorf("Cycles = %s, want [[1 2 3 1]]", got)
+}
+if len(result.Files) != 4 {
+t.Errorf("resolved %d files, want 4", len(result.Files))
+}
+
+reasons := make(map[int]UnresolvedReason)
+for _, unresolved := range result.Unresolved {
+reasons[unresolved.ModID] = unresolved.Reason
+}
+if reasons[9] != UnresolvedLookupFailed || reasons[4] != UnresolvedIncompatible {
+t.Errorf("Unresolved = %+v", result.Unresolved)
+}
+
+// The back-edge closing the cycle is recorded on the mod it returns to
+for _, resolved := range result.Files {
+if resolved.File.ModID == 1 && fmt.Sprint(resolved.RequiredBy) != "[3]" {
+t.Errorf("mod 1 RequiredBy = %v, want [3]", resolved.RequiredBy)
+}
+}
+}
+
+func TestResolveDependenciesSharedUnresolved(t *testing.T) {
+catalog := map[int][]File{
+1: {resolverTestFile(10, 1, 1, []string{"1.20.1"}, requiredDependency(3))},
+2: {resolverTestFile(20, 2, 1, []string{"1.20.1"}, requiredDependency(3))},
+3: {resolverTestFile(30, 3, 1, []string{"1.19.2"})},
+}
+listFiles := func(modID int) ([]File, error) {
+return catalog[modID], nil
+}
+
+result := resolveDependencies([]int{1, 2}, ResolveOptions{FileConstraints: FileConstraints{GameVersion: "1.20.1"}}, listFiles)
+
+var requiredBy []int
+for _, unresolved := range result.Unresolved {
+if unresolved.ModID != 3 || unresolved.Reason != UnresolvedNoCompatibleFile {
+t.Errorf("unexpected unresolved dependency %+v", unresolved)
+}
+requiredBy = append(requiredBy, unresolved.RequiredBy)
+}
+if fmt.Sprint(requiredBy) != "[1 2]" {
+t.Errorf("mod 3 reported as required by %v, want [1 2]", requiredBy)
+}
+}
+
+func TestResolveDependenciesPaginates(t *testing.T) {
+var pages int
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+if !strings.HasPrefix(r.URL.Path, "/v1/mods/1/files") {
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+return
+}
+if r.URL.Query().Get("gameVersion") != "1.20.1" {
+t.Errorf("gameVersion = %q", r.URL.Query().Get("gameVersion"))
+}
+pages++
+index, _ := strconv.Atoi(r.URL.Query().Get("index"))
+file := resolverTestFile(100+index, 1, 1+index, []string{"1.20.1"})
+json.NewEncoder(w).Encode(PaginatedResponse[[]File]{
+Data:       []File{file},
+Pagination: Pagination{Index: index, PageSize: 1, ResultCount: 1, TotalCount: 3},
+})
+}))
+defer server.Close()
+
+result, err := ResolveDependencies(NewServerWithURL("key", server.URL), []int{1}, ResolveOptions{FileConstraints: FileConstraints{GameVersion: "1.20.1"}})
+if err != nil {
+t.Fatalf("ResolveDependencies failed: %v", err)
+}
+if pages != 3 {
+t.Errorf("fetched %d pages, want 3", pages)
+}
+if got := fmt.Sprint(result.FileIDs()); got != "[102]" {
+t.Errorf("FileIDs() = %s, want newest file from the last page", got)
+}
+}
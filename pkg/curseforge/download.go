@@ -0,0 +1,305 @@
+package curseforge
+
+import (
+"errors"
+"fmt"
+"io"
+"net/http"
+"os"
+"strconv"
+"strings"
+"time"
+)
+
+// Suffix of the temporary file a download is written to before it is verified
+const DownloadPartSuffix = ".part"
+
+// ErrDownloadURLUnavailable is matched by errors for files without a download URL
+// DownloadFileWithOptions returns a *ManualDownloadRequired wrapping it
+var ErrDownloadURLUnavailable = errors.New("download URL is not available for this file")
+
+// DownloadOptions configures DownloadFileWithOptions
+type DownloadOptions struct {
+// VerifyFingerprint also checks File.FileFingerprint, which needs one
+// extra read of the downloaded file
+VerifyFingerprint bool
+
+// DisableResume discards an existing partial download instead of
+// continuing it with an HTTP Range request
+DisableResume bool
+
+// HTTPClient is used for the request, defaults to http.DefaultClient
+HTTPClient *http.Client
+
+// OnProgress receives progress events at most once per ProgressInterval
+// (DefaultProgressInterval when unset); see ProgressChannel for a channel adapter
+OnProgress       ProgressFunc
+ProgressInterval time.Duration
+
+// RateLimiter caps throughput; share one limiter between concurrent downloads
+// to cap their combined bandwidth
+RateLimiter *RateLimiter
+
+// FS is the filesystem files are written to, defaults to OSFS
+FS WritableFS
+
+// Store, when set, keeps downloaded files in a shared BlobStore: a file
+// already stored is linked or copied from it instead of being downloaded.
+// Files without a SHA1 hash bypass the store. Installed files are recorded
+// against StoreInstance, which must be registered with the store, so
+// BlobStore.GarbageCollect keeps their blobs while they exist on the OS
+// filesystem; files written to another FS do not keep their blobs alive.
+Store         *BlobStore
+StoreInstance string
+}
+
+// HashMismatchError is returned when a downloaded file does not match the
+// length or hashes CurseForge published for it
+type HashMismatchError struct {
+FileID   int
+FileName string
+Check    string // "length", "sha1", "md5" or "fingerprint"
+Expected string
+Actual   string
+}
+
+func (e *HashMismatchError) Error() string {
+return fmt.Sprintf("download of %s (file %d) failed %s verification: expected %s, got %s", e.FileName, e.FileID, e.Check, e.Expected, e.Actual)
+}
+
+// DownloadStatusError is returned when the download server answers with an unexpected status
+type DownloadStatusError struct {
+FileID     int
+StatusCode int
+}
+
+func (e *DownloadStatusError) Error() string {
+return fmt.Sprintf("download failed with status: %d", e.StatusCode)
+}
+
+// Permanent reports whether retrying cannot help: a 4xx status other than
+// 408 Request Timeout and 429 Too Many Requests
+func (e *DownloadStatusError) Permanent() bool {
+return e.StatusCode >= 400 && e.StatusCode < 500 &&
+e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+// VerifyHashes compares computed hashes against the length, SHA1, MD5 and
+// (when available and requested) fingerprint published for the file
+func VerifyHashes(file File, hashes *FileHashes, checkFingerprint bool) error {
+mismatch := func(check string, expected string, actual string) error {
+return &HashMismatchError{FileID: file.ID, FileName: file.FileName, Check: check, Expected: expected, Actual: actual}
+}
+
+if file.FileLength > 0 && hashes.Size != file.FileLength {
+return mismatch("length", strconv.FormatInt(file.FileLength, 10), strconv.FormatInt(hashes.Size, 10))
+}
+if expected := GetSha1Hash(file); expected != "" && !strings.EqualFold(expected, hashes.Sha1) {
+return mismatch("sha1", expected, hashes.Sha1)
+}
+if expected := GetMd5Hash(file); expected != "" && !strings.EqualFold(expected, hashes.Md5) {
+return mismatch("md5", expected, hashes.Md5)
+}
+if checkFingerprint && file.FileFingerprint != 0 && hashes.HasFingerprint && hashes.Fingerprint != file.FileFingerprint {
+return mismatch("fingerprint", strconv.FormatInt(file.FileFingerprint, 10), strconv.FormatInt(hashes.Fingerprint, 10))
+}
+return nil
+}
+
+// DownloadFileWithOptions downloads a mod file to destination
+//
+// The file is written to destination+DownloadPartSuffix and only renamed into
+// place once its length and hashes match File.FileLength and File.Hashes, so
+// destination never holds a truncated or corrupt file. A partial download left
+// behind by an interrupted attempt is resumed with an HTTP Range request; a
+// download that fails verification is removed and a *HashMismatchError returned.
+// Files whose author disallows third-party distribution have no download URL;
+// for those a *ManualDownloadRequired is returned.
+// With OnProgress set, a final Done event is sent once the file is in place,
+// or with Err set when the download fails.
+func DownloadFileWithOptions(file File, destination string, options DownloadOptions) error {
+if options.Store != nil && GetSha1Hash(file) != "" {
+return installFromStore(file, destination, options)
+}
+
+var progress *progressWriter
+if options.OnProgress != nil {
+progress = newProgressWriter(file, options.OnProgress, options.ProgressInterval, 0, file.FileLength)
+}
+err := downloadFile(file, destination, options, progress)
+if progress != nil {
+progress.finish(err)
+}
+return err
+}
+
+// downloadFile implements DownloadFileWithOptions, reporting to progress when it is not nil
+func downloadFile(file File, destination string, options DownloadOptions, progress *progressWriter) error {
+if file.DownloadURL == "" {
+return NewManualDownloadRequired(file, nil, destination)
+}
+
+client := options.HTTPClient
+if client == nil {
+client = http.DefaultClient
+}
+
+fsys := orOSFS(options.FS)
+if err := fsys.MkdirAll(dirOf(fsys, destination), 0o755); err != nil {
+return err
+}
+
+partPath := destination + DownloadPartSuffix
+flags := os.O_RDWR | os.O_CREATE
+if options.DisableResume {
+flags |= os.O_TRUNC
+}
+part, err := fsys.OpenFile(partPath, flags, 0o644)
+if err != nil {
+return err
+}
+defer part.Close()
+
+hashes, err := downloadToPart(client, file, part, options, progress)
+if err != nil {
+return err
+}
+
+if options.VerifyFingerprint && file.FileFingerprint != 0 {
+if _, err := part.Seek(0, io.SeekStart); err != nil {
+return err
+}
+if err := hashes.CompleteFingerprint(part); err != nil {
+return fmt.Errorf("failed to compute fingerprint: %w", err)
+}
+}
+
+if err := VerifyHashes(file, hashes, options.VerifyFingerprint); err != nil {
+part.Close()
+fsys.Remove(partPath)
+return err
+}
+
+if err := part.Sync(); err != nil {
+return err
+}
+if err := part.Close(); err != nil {
+return err
+}
+return fsys.Rename(partPath, destination)
+}
+
+// downloadToPart hashes any partial content already in part, requests the
+// remainder and appends it, returning the hashes of the complete content
+func downloadToPart(client *http.Client, file File, part WritableFile, options DownloadOptions, progress *progressWriter) (*FileHashes, error) {
+hasher := NewMultiHasher()
+offset, err := io.Copy(hasher, part)
+if err != nil {
+return nil, fmt.Errorf("failed to read partial download: %w", err)
+}
+
+if file.FileLength > 0 && offset > file.FileLength {
+contextLogger.Trace(fmt.Sprintf("discarding oversized partial download of %s", file.FileName))
+if hasher, offset, err = restartPart(part); err != nil {
+return nil, err
+}
+}
+if file.FileLength > 0 && offset == file.FileLength {
+if progress != nil {
+progress.resume(offset, file.FileLength)
+}
+return hasher.Hashes()
+}
+
+resp, err := requestDownload(client, file.DownloadURL, offset)
+if err != nil {
+return nil, err
+}
+defer resp.Body.Close()
+
+switch {
+case resp.StatusCode == http.StatusPartialContent && offset > 0:
+if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+return nil, fmt.Errorf("download resumed at unexpected range %q", resp.Header.Get("Content-Range"))
+}
+contextLogger.Trace(fmt.Sprintf("resuming download of %s at byte %d", file.FileName, offset))
+case resp.StatusCode == http.StatusOK:
+if offset > 0 {
+// The server ignored the Range header and sent the whole file
+if hasher, offset, err = restartPart(part); err != nil {
+return nil, err
+}
+}
+case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+// The partial file is already complete (or invalid); verification decides
+if progress != nil {
+progress.resume(offset, offset)
+}
+return hasher.Hashes()
+default:
+return nil, &DownloadStatusError{FileID: file.ID, StatusCode: resp.StatusCode}
+}
+
+var body io.Reader = resp.Body
+if options.RateLimiter != nil {
+body = &rateLimitedReader{reader: body, limiter: options.RateLimiter}
+}
+
+writer := io.MultiWriter(part, hasher)
+if progress != nil {
+total := file.FileLength
+if total <= 0 && resp.ContentLength > 0 {
+total = offset + resp.ContentLength
+}
+progress.resume(offset, total)
+writer = io.MultiWriter(part, hasher, progress)
+}
+
+if _, err := io.Copy(writer, body); err != nil {
+return nil, fmt.Errorf("download interrupted: %w", err)
+}
+return hasher.Hashes()
+}
+
+// restartPart truncates a partial download so it starts again from zero
+func restartPart(part WritableFile) (*MultiHasher, int64, error) {
+if err := part.Truncate(0); err != nil {
+return nil, 0, err
+}
+if _, err := part.Seek(0, io.SeekStart); err != nil {
+return nil, 0, err
+}
+return NewMultiHasher(), 0, nil
+}
+
+// requestDownload issues the GET request, asking for the bytes from offset on
+func requestDownload(client *http.Client, downloadURL string, offset int64) (*http.Response, error) {
+req, err := http.NewRequest(http.MethodGet, downloadURL, nil)
+if err != nil {
+return nil, fmt.Errorf("failed to create request: %w", err)
+}
+if offset > 0 {
+req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+}
+return client.Do(req)
+}
+
+// contentRangeStart parses the first byte position of a Content-Range header
+func contentRangeStart(header string) (int64, bool) {
+spec, ok := strings.CutPrefix(header, "bytes ")
+if !ok {
+return 0, false
+}
+startStr, _, ok := strings.Cut(spec, "-")
+if !ok {
+return 0, false
+}
+start, err := strconv.ParseInt(startStr, 10, 64)
+return start, err == nil
+}
+
+// IsHashMismatch reports whether err is (or wraps) a *HashMismatchError
+func IsHashMismatch(err error) bool {
+var mismatch *HashMismatchError
+return errors.As(err, &mismatch)
+}
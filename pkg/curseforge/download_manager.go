@@ -0,0 +1,218 @@
+package curseforge
+
+import (
+"errors"
+"fmt"
+"path/filepath"
+"sync"
+"time"
+)
+
+// Default values used by DownloadFiles
+const (
+DefaultDownloadWorkers     = 4
+DefaultDownloadAttempts    = 3
+DefaultDownloadRetryDelay  = time.Second
+maxDownloadRetryDelayShift = 5
+)
+
+// DownloadRequest pairs a file with the path it should be downloaded to
+type DownloadRequest struct {
+File        File
+Destination string
+}
+
+// DownloadManagerOptions configures DownloadFiles
+type DownloadManagerOptions struct {
+Workers     int           // concurrent downloads, defaults to DefaultDownloadWorkers
+MaxAttempts int           // attempts per file, defaults to DefaultDownloadAttempts
+RetryDelay  time.Duration // delay before the first retry, doubled for each further retry
+
+// Download is applied to every file; its OnProgress and RateLimiter are
+// shared by all downloads (events carry the FileID), and every attempt ends
+// with its own Done event
+Download DownloadOptions
+}
+
+// DownloadResult is the outcome of a single request
+type DownloadResult struct {
+Request  DownloadRequest
+Attempts int
+Err      error
+}
+
+// DownloadSummary collects the outcome of DownloadFiles
+type DownloadSummary struct {
+Succeeded  []DownloadResult
+Failed     []DownloadResult
+Duplicates []DownloadRequest // requests dropped because they were identical to another
+
+// ManualDownloads lists files that must be downloaded by the user because
+// their author disallows third-party distribution; they are not in Failed
+ManualDownloads []*ManualDownloadRequired
+}
+
+// Err returns the per-file errors joined together, or nil if every file succeeded
+// Files requiring a manual download are reported in ManualDownloads, not here
+func (s *DownloadSummary) Err() error {
+var errs []error
+for _, result := range s.Failed {
+errs = append(errs, fmt.Errorf("%s: %w", result.Request.Destination, result.Err))
+}
+return errors.Join(errs...)
+}
+
+// downloadGroup is one network download plus the destinations it is copied to
+type downloadGroup struct {
+primary int   // index into the deduplicated requests
+copies  []int // further requests for the same File.ID
+}
+
+// DownloadFiles downloads many files with bounded parallelism
+//
+// Identical requests are dropped, and a File.ID requested for several
+// destinations is downloaded once and copied to the others. Every file is
+// retried independently; failures are collected in the summary instead of
+// stopping the remaining downloads.
+func DownloadFiles(requests []DownloadRequest, options DownloadManagerOptions) *DownloadSummary {
+if options.Workers <= 0 {
+options.Workers = DefaultDownloadWorkers
+}
+if options.MaxAttempts <= 0 {
+options.MaxAttempts = DefaultDownloadAttempts
+}
+if options.RetryDelay <= 0 {
+options.RetryDelay = DefaultDownloadRetryDelay
+}
+
+summary := &DownloadSummary{}
+unique, groups, conflicts := groupDownloadRequests(requests, summary)
+
+results := make([]DownloadResult, len(unique))
+for idx, err := range conflicts {
+results[idx] = DownloadResult{Request: unique[idx], Err: err}
+}
+
+jobs := make(chan downloadGroup)
+var wg sync.WaitGroup
+for i := 0; i < options.Workers; i++ {
+wg.Add(1)
+go func() {
+defer wg.Done()
+for group := range jobs {
+primary := unique[group.primary]
+attempts, err := downloadWithRetries(primary, options)
+results[group.primary] = DownloadResult{Request: primary, Attempts: attempts, Err: err}
+
+for _, idx := range group.copies {
+copyErr := err
+if copyErr == nil {
+copyErr = copyFileFS(options.Download.FS, primary.Destination, options.Download.FS, unique[idx].Destination)
+}
+results[idx] = DownloadResult{Request: unique[idx], Attempts: attempts, Err: copyErr}
+}
+}
+}()
+}
+
+for _, group := range groups {
+jobs <- group
+}
+close(jobs)
+wg.Wait()
+
+for _, result := range results {
+if manual, ok := AsManualDownloadRequired(result.Err); ok {
+// Copies of a File.ID group share the primary's error, so each result gets its own value
+required := *manual
+required.Destination = result.Request.Destination
+summary.ManualDownloads = append(summary.ManualDownloads, &required)
+} else if result.Err != nil {
+summary.Failed = append(summary.Failed, result)
+} else {
+summary.Succeeded = append(summary.Succeeded, result)
+}
+}
+return summary
+}
+
+// groupDownloadRequests drops duplicate requests, groups the rest by File.ID
+// and flags destinations requested for different files as conflicts
+func groupDownloadRequests(requests []DownloadRequest, summary *DownloadSummary) ([]DownloadRequest, []downloadGroup, map[int]error) {
+var unique []DownloadRequest
+var groups []downloadGroup
+conflicts := make(map[int]error)
+
+fileByDestination := make(map[string]int)
+groupByFileID := make(map[int]int)
+
+for _, request := range requests {
+destination := filepath.Clean(request.Destination)
+if fileID, ok := fileByDestination[destination]; ok {
+if fileID == request.File.ID {
+summary.Duplicates = append(summary.Duplicates, request)
+} else {
+conflicts[len(unique)] = fmt.Errorf("destination is also requested for file %d", fileID)
+unique = append(unique, request)
+}
+continue
+}
+fileByDestination[destination] = request.File.ID
+
+idx := len(unique)
+unique = append(unique, request)
+
+// Files without an ID cannot be recognised as the same download
+if groupIdx, ok := groupByFileID[request.File.ID]; ok && request.File.ID != 0 {
+groups[groupIdx].copies = append(groups[groupIdx].copies, idx)
+continue
+}
+groupByFileID[request.File.ID] = len(groups)
+groups = append(groups, downloadGroup{primary: idx})
+}
+return unique, groups, conflicts
+}
+
+// downloadWithRetries downloads a single request, backing off between attempts
+// A hash mismatch is retried once, in case the content was corrupted in transit
+func downloadWithRetries(request DownloadRequest, options DownloadManagerOptions) (int, error) {
+var err error
+mismatches := 0
+for attempt := 1; attempt <= options.MaxAttempts; attempt++ {
+err = DownloadFileWithOptions(request.File, request.Destination, options.Download)
+if err == nil || !isRetryableDownloadError(err) {
+return attempt, err
+}
+if IsHashMismatch(err) {
+mismatches++
+if mismatches > 1 {
+return attempt, err
+}
+}
+if attempt == options.MaxAttempts {
+return attempt, err
+}
+
+shift := attempt - 1
+if shift > maxDownloadRetryDelayShift {
+shift = maxDownloadRetryDelayShift
+}
+delay := options.RetryDelay << shift
+contextLogger.Trace(fmt.Sprintf("retrying download of %s in %s: %v", request.File.FileName, delay, err))
+time.Sleep(delay)
+}
+return options.MaxAttempts, err
+}
+
+// isRetryableDownloadError reports whether another attempt might succeed
+// Missing download URLs and permanent HTTP errors (see DownloadStatusError.Permanent) are not retried
+func isRetryableDownloadError(err error) bool {
+if errors.Is(err, ErrDownloadURLUnavailable) {
+return false
+}
+var status *DownloadStatusError
+if errors.As(err, &status) && status.Permanent() {
+return false
+}
+return true
+}
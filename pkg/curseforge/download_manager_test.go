@@ -0,0 +1,179 @@
+package curseforge
+
+import (
+"bytes"
+"errors"
+"net/http"
+"net/http/httptest"
+"os"
+"path/filepath"
+"strings"
+"sync"
+"sync/atomic"
+"testing"
+"time"
+)
+
+func TestDownloadFiles(t *testing.T) {
+contents := map[string][]byte{
+"/a.jar":     fingerprintTestData(3000),
+"/b.jar":     fingerprintTestData(4000),
+"/flaky.jar": fingerprintTestData(5000),
+}
+
+var mu sync.Mutex
+requests := make(map[string]int)
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+mu.Lock()
+requests[r.URL.Path]++
+count := requests[r.URL.Path]
+mu.Unlock()
+
+content, ok := contents[r.URL.Path]
+if !ok {
+w.WriteHeader(http.StatusNotFound)
+return
+}
+if r.URL.Path == "/flaky.jar" && count == 1 {
+w.WriteHeader(http.StatusInternalServerError)
+return
+}
+http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(content))
+}))
+defer server.Close()
+
+file := func(id int, name string) File {
+f := testDownloadFile(server.URL+"/"+name, contents["/"+name])
+f.ID = id
+f.FileName = name
+return f
+}
+
+dir := t.TempDir()
+a := file(1, "a.jar")
+summary := DownloadFiles([]DownloadRequest{
+{File: a, Destination: filepath.Join(dir, "one", "a.jar")},
+{File: a, Destination: filepath.Join(dir, "one", "a.jar")},
+{File: a, Destination: filepath.Join(dir, "two", "a.jar")},
+{File: file(2, "b.jar"), Destination: filepath.Join(dir, "one", "b.jar")},
+{File: file(3, "flaky.jar"), Destination: filepath.Join(dir, "one", "flaky.jar")},
+{File: File{ID: 4, FileName: "missing.jar", DownloadURL: server.URL + "/missing.jar"}, Destination: filepath.Join(dir, "one", "missing.jar")},
+{File: File{ID: 5, FileName: "manual.jar"}, Destination: filepath.Join(dir, "one", "manual.jar")},
+{File: file(2, "b.jar"), Destination: filepath.Join(dir, "one", "a.jar")},
+}, DownloadManagerOptions{Workers: 3, RetryDelay: time.Millisecond})
+
+if len(summary.Duplicates) != 1 {
+t.Errorf("Duplicates = %d, want 1", len(summary.Duplicates))
+}
+if len(summary.Succeeded) != 4 {
+t.Errorf("Succeeded = %d, want 4", len(summary.Succeeded))
+}
+if len(summary.Failed) != 2 {
+t.Fatalf("Failed = %d, want 2: %v", len(summary.Failed), summary.Err())
+}
+if len(summary.ManualDownloads) != 1 || summary.ManualDownloads[0].FileID != 5 {
+t.Errorf("ManualDownloads = %+v", summary.ManualDownloads)
+}
+
+for _, name := range []string{"one/a.jar", "two/a.jar"} {
+data, err := os.ReadFile(filepath.Join(dir, name))
+if err != nil || !bytes.Equal(data, contents["/a.jar"]) {
+t.Errorf("%s content differs (err=%v)", name, err)
+}
+}
+if requests["/a.jar"] != 1 {
+t.Errorf("a.jar requested %d times, want 1", requests["/a.jar"])
+}
+if requests["/missing.jar"] != 1 {
+t.Errorf("missing.jar requested %d times, want 1 since 404 is permanent", requests["/missing.jar"])
+}
+
+for _, result := range summary.Succeeded {
+if result.Request.File.ID == 3 && result.Attempts != 2 {
+t.Errorf("flaky.jar took %d attempts, want 2", result.Attempts)
+}
+}
+if summary.Err() == nil {
+t.Error("Err returned nil with failed downloads")
+}
+}
+
+func TestDownloadRetryClassification(t *testing.T) {
+tests := []struct {
+name   string
+status int
+want   int // requests made
+}{
+{"not found", http.StatusNotFound, 1},
+{"forbidden", http.StatusForbidden, 1},
+{"request timeout", http.StatusRequestTimeout, 3},
+{"too many requests", http.StatusTooManyRequests, 3},
+{"server error", http.StatusBadGateway, 3},
+}
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+var requests atomic.Int32
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+requests.Add(1)
+w.WriteHeader(tt.status)
+}))
+defer server.Close()
+
+file := File{ID: 1, FileName: "mod.jar", DownloadURL: server.URL + "/mod.jar"}
+summary := DownloadFiles([]DownloadRequest{{File: file, Destination: filepath.Join(t.TempDir(), "mod.jar")}},
+DownloadManagerOptions{MaxAttempts: 3, RetryDelay: time.Millisecond})
+if len(summary.Failed) != 1 {
+t.Fatalf("Failed = %d, want 1", len(summary.Failed))
+}
+var status *DownloadStatusError
+if !errors.As(summary.Failed[0].Err, &status) || status.StatusCode != tt.status {
+t.Errorf("error = %v, want DownloadStatusError %d", summary.Failed[0].Err, tt.status)
+}
+if got := int(requests.Load()); got != tt.want {
+t.Errorf("requests = %d, want %d", got, tt.want)
+}
+})
+}
+
+t.Run("hash mismatch", func(t *testing.T) {
+content := fingerprintTestData(1000)
+server := newTestDownloadServer(t, content)
+file := testDownloadFile(server.URL, content)
+file.Hashes[0].Value = strings.Repeat("0", 40)
+
+summary := DownloadFiles([]DownloadRequest{{File: file, Destination: filepath.Join(t.TempDir(), "mod.jar")}},
+DownloadManagerOptions{MaxAttempts: 5, RetryDelay: time.Millisecond})
+if len(summary.Failed) != 1 || !IsHashMismatch(summary.Failed[0].Err) {
+t.Fatalf("expected a hash mismatch, got %v", summary.Err())
+}
+if summary.Failed[0].Attempts != 2 || server.requests.Load() != 2 {
+t.Errorf("hash mismatch attempted %d times (%d requests), want 2", summary.Failed[0].Attempts, server.requests.Load())
+}
+})
+}
+
+func TestDownloadFilesManualDownloadDestinations(t *testing.T) {
+dir := t.TempDir()
+manual := File{ID: 7, ModID: 70, FileName: "m.jar"}
+one := filepath.Join(dir, "one", "m.jar")
+two := filepath.Join(dir, "two", "m.jar")
+
+summary := DownloadFiles([]DownloadRequest{
+{File: manual, Destination: one},
+{File: manual, Destination: two},
+}, DownloadManagerOptions{RetryDelay: time.Millisecond})
+
+if len(summary.ManualDownloads) != 2 {
+t.Fatalf("ManualDownloads = %d, want 2", len(summary.ManualDownloads))
+}
+if summary.ManualDownloads[0] == summary.ManualDownloads[1] {
+t.Error("manual downloads share one value")
+}
+destinations := map[string]bool{}
+for _, required := range summary.ManualDownloads {
+destinations[required.Destination] = true
+}
+if !destinations[one] || !destinations[two] {
+t.Errorf("destinations = %v, want %s and %s", destinations, one, two)
+}
+}
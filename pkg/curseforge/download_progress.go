@@ -0,0 +1,209 @@
+package curseforge
+
+import (
+"io"
+"sync"
+"time"
+)
+
+// DefaultProgressInterval is the minimum time between progress callbacks
+const DefaultProgressInterval = 250 * time.Millisecond
+
+// DownloadProgress describes the state of a running download
+type DownloadProgress struct {
+FileID     int
+FileName   string
+BytesDone  int64         // bytes on disk, including resumed content
+TotalBytes int64         // from File.FileLength or Content-Length, 0 if unknown
+Rate       float64       // average bytes per second for the current transfer
+ETA        time.Duration // estimated time remaining, 0 if unknown
+Done       bool          // set on the final event, successful or not
+Err        error         // set on the final event of a failed transfer
+}
+
+// Percent returns the completed percentage, or -1 if the total is unknown
+func (p DownloadProgress) Percent() float64 {
+if p.TotalBytes <= 0 {
+return -1
+}
+return float64(p.BytesDone) * 100 / float64(p.TotalBytes)
+}
+
+// ProgressFunc receives download progress events
+// It is called from the downloading goroutine and should return quickly
+type ProgressFunc func(DownloadProgress)
+
+// ProgressChannel returns a ProgressFunc delivering events to ch
+// Events are dropped rather than blocking the download when ch is full,
+// except for the final Done event which is always delivered
+func ProgressChannel(ch chan<- DownloadProgress) ProgressFunc {
+return func(progress DownloadProgress) {
+if progress.Done {
+ch <- progress
+return
+}
+select {
+case ch <- progress:
+default:
+}
+}
+}
+
+// progressWriter counts bytes written and reports them at most once per interval
+type progressWriter struct {
+progress DownloadProgress
+callback ProgressFunc
+interval time.Duration
+start    time.Time
+resumed  int64
+last     time.Time
+finished bool
+}
+
+func newProgressWriter(file File, callback ProgressFunc, interval time.Duration, offset int64, total int64) *progressWriter {
+if interval <= 0 {
+interval = DefaultProgressInterval
+}
+now := time.Now()
+return &progressWriter{
+progress: DownloadProgress{FileID: file.ID, FileName: file.FileName, BytesDone: offset, TotalBytes: total},
+callback: callback,
+interval: interval,
+start:    now,
+resumed:  offset,
+last:     now,
+}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+w.progress.BytesDone += int64(len(p))
+if now := time.Now(); now.Sub(w.last) >= w.interval {
+w.last = now
+w.report(now)
+}
+return len(p), nil
+}
+
+// report computes rate and ETA and invokes the callback
+func (w *progressWriter) report(now time.Time) {
+elapsed := now.Sub(w.start).Seconds()
+transferred := w.progress.BytesDone - w.resumed
+if elapsed > 0 {
+w.progress.Rate = float64(transferred) / elapsed
+}
+w.progress.ETA = 0
+if w.progress.Rate > 0 && w.progress.TotalBytes > w.progress.BytesDone {
+remaining := float64(w.progress.TotalBytes - w.progress.BytesDone)
+w.progress.ETA = time.Duration(remaining / w.progress.Rate * float64(time.Second))
+}
+w.callback(w.progress)
+}
+
+// resume restarts the rate measurement for a transfer continuing at offset
+func (w *progressWriter) resume(offset int64, total int64) {
+now := time.Now()
+w.progress.BytesDone = offset
+w.progress.TotalBytes = total
+w.resumed = offset
+w.start = now
+w.last = now
+}
+
+// finish reports the final event, carrying err if the transfer failed
+// Only the first call reports
+func (w *progressWriter) finish(err error) {
+if w.finished {
+return
+}
+w.finished = true
+w.progress.Done = true
+w.progress.Err = err
+w.report(time.Now())
+}
+
+// RateLimiter caps the combined throughput of every download sharing it
+// It is a token bucket holding at most one second worth of bytes
+type RateLimiter struct {
+mu     sync.Mutex
+rate   float64
+tokens float64
+last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing bytesPerSecond across all users
+// A value <= 0 disables limiting
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+return &RateLimiter{
+rate:   float64(bytesPerSecond),
+tokens: float64(bytesPerSecond),
+last:   time.Now(),
+}
+}
+
+// SetRate changes the limit; it applies to subsequent reads of every download
+func (l *RateLimiter) SetRate(bytesPerSecond int64) {
+l.mu.Lock()
+defer l.mu.Unlock()
+l.rate = float64(bytesPerSecond)
+if l.tokens > l.rate {
+l.tokens = l.rate
+}
+}
+
+// burst returns the largest amount of bytes that may be requested at once
+func (l *RateLimiter) burst() int {
+l.mu.Lock()
+defer l.mu.Unlock()
+if l.rate <= 0 {
+return 0
+}
+if l.rate < 1 {
+return 1
+}
+return int(l.rate)
+}
+
+// WaitN blocks until n bytes may be transferred
+// The bytes are reserved immediately, so concurrent callers queue fairly
+func (l *RateLimiter) WaitN(n int) {
+l.mu.Lock()
+if l.rate <= 0 {
+l.mu.Unlock()
+return
+}
+
+now := time.Now()
+l.tokens += now.Sub(l.last).Seconds() * l.rate
+if l.tokens > l.rate {
+l.tokens = l.rate
+}
+l.last = now
+
+l.tokens -= float64(n)
+var wait time.Duration
+if l.tokens < 0 {
+wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+l.mu.Unlock()
+
+if wait > 0 {
+time.Sleep(wait)
+}
+}
+
+// rateLimitedReader throttles reads through a shared RateLimiter
+type rateLimitedReader struct {
+reader  io.Reader
+limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+if burst := r.limiter.burst(); burst > 0 && len(p) > burst {
+p = p[:burst]
+}
+n, err := r.reader.Read(p)
+if n > 0 {
+r.limiter.WaitN(n)
+}
+return n, err
+}
@@ -0,0 +1,116 @@
+package curseforge
+
+import (
+"io"
+"net/http"
+)
+
+// verifyingReadCloser hashes a download as it is read and verifies it at EOF
+type verifyingReadCloser struct {
+body     io.ReadCloser
+reader   io.Reader
+file     File
+hasher   *MultiHasher
+progress *progressWriter
+err      error
+}
+
+func (v *verifyingReadCloser) Read(p []byte) (int, error) {
+if v.err != nil {
+return 0, v.err
+}
+
+n, err := v.reader.Read(p)
+if n > 0 {
+v.hasher.Write(p[:n])
+if v.progress != nil {
+v.progress.Write(p[:n])
+}
+}
+
+if err == io.EOF {
+hashes, hashErr := v.hasher.Hashes()
+if hashErr == nil {
+hashErr = VerifyHashes(v.file, hashes, false)
+}
+if hashErr != nil {
+err = hashErr
+}
+}
+if err != nil {
+v.err = err
+if v.progress != nil {
+finalErr := err
+if err == io.EOF {
+finalErr = nil
+}
+v.progress.finish(finalErr)
+}
+}
+return n, err
+}
+
+func (v *verifyingReadCloser) Close() error {
+return v.body.Close()
+}
+
+// OpenDownload starts downloading file and returns its content as a stream
+//
+// The length, SHA1 and MD5 are verified as the stream is consumed: instead of
+// io.EOF the final Read returns a *HashMismatchError if the content does not
+// match File.FileLength and File.Hashes, so callers must treat any error other
+// than io.EOF as a failed download. The fingerprint needs a second read of the
+// content and is not verified; DownloadOptions.VerifyFingerprint is ignored.
+// OnProgress and RateLimiter apply as with DownloadFileWithOptions.
+// The caller must close the returned stream.
+func OpenDownload(file File, options DownloadOptions) (io.ReadCloser, error) {
+if file.DownloadURL == "" {
+return nil, NewManualDownloadRequired(file, nil, "")
+}
+
+client := options.HTTPClient
+if client == nil {
+client = http.DefaultClient
+}
+
+resp, err := requestDownload(client, file.DownloadURL, 0)
+if err != nil {
+return nil, err
+}
+if resp.StatusCode != http.StatusOK {
+resp.Body.Close()
+return nil, &DownloadStatusError{FileID: file.ID, StatusCode: resp.StatusCode}
+}
+
+stream := &verifyingReadCloser{
+body:   resp.Body,
+reader: resp.Body,
+file:   file,
+hasher: NewMultiHasher(),
+}
+if options.RateLimiter != nil {
+stream.reader = &rateLimitedReader{reader: resp.Body, limiter: options.RateLimiter}
+}
+if options.OnProgress != nil {
+total := file.FileLength
+if total <= 0 && resp.ContentLength > 0 {
+total = resp.ContentLength
+}
+stream.progress = newProgressWriter(file, options.OnProgress, options.ProgressInterval, 0, total)
+}
+return stream, nil
+}
+
+// DownloadTo downloads file into w, e.g. a zip entry or an upload stream, and
+// returns the number of bytes written
+// A *HashMismatchError is returned if the content fails verification; since
+// the bytes were already written, the caller must discard what w received
+func DownloadTo(w io.Writer, file File, options DownloadOptions) (int64, error) {
+stream, err := OpenDownload(file, options)
+if err != nil {
+return 0, err
+}
+defer stream.Close()
+
+return io.Copy(w, stream)
+}
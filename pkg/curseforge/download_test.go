@@ -0,0 +1,315 @@
+package curseforge
+
+import (
+"bytes"
+"crypto/md5"
+"crypto/sha1"
+"encoding/hex"
+"errors"
+"io"
+"net/http"
+"net/http/httptest"
+"os"
+"path/filepath"
+"strings"
+"sync"
+"sync/atomic"
+"testing"
+"time"
+)
+
+// testDownloadServer serves content with Range support and counts requests
+type testDownloadServer struct {
+*httptest.Server
+content      []byte
+requests     atomic.Int32
+rangeHeaders atomic.Int32
+}
+
+func newTestDownloadServer(t *testing.T, content []byte) *testDownloadServer {
+s := &testDownloadServer{content: content}
+s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+s.requests.Add(1)
+if r.Header.Get("Range") != "" {
+s.rangeHeaders.Add(1)
+}
+http.ServeContent(w, r, "mod.jar", time.Time{}, bytes.NewReader(s.content))
+}))
+t.Cleanup(s.Close)
+return s
+}
+
+// testDownloadFile describes content the way the CurseForge API does
+func testDownloadFile(url string, content []byte) File {
+sha1Sum := sha1.Sum(content)
+md5Sum := md5.Sum(content)
+return File{
+ID:              42,
+FileName:        "mod.jar",
+DownloadURL:     url,
+FileLength:      int64(len(content)),
+FileFingerprint: ComputeFingerprint(content),
+Hashes: []FileHash{
+{Value: hex.EncodeToString(sha1Sum[:]), Algo: HashAlgoSha1},
+{Value: hex.EncodeToString(md5Sum[:]), Algo: HashAlgoMd5},
+},
+}
+}
+
+func TestDownloadFile(t *testing.T) {
+content := fingerprintTestData(100000)
+server := newTestDownloadServer(t, content)
+destination := filepath.Join(t.TempDir(), "mods", "mod.jar")
+
+err := DownloadFileWithOptions(testDownloadFile(server.URL, content), destination, DownloadOptions{VerifyFingerprint: true})
+if err != nil {
+t.Fatalf("DownloadFileWithOptions failed: %v", err)
+}
+
+data, err := os.ReadFile(destination)
+if err != nil || !bytes.Equal(data, content) {
+t.Fatalf("downloaded content differs (err=%v)", err)
+}
+if _, err := os.Stat(destination + DownloadPartSuffix); !os.IsNotExist(err) {
+t.Error("partial file left behind after a successful download")
+}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+content := fingerprintTestData(100000)
+server := newTestDownloadServer(t, content)
+destination := filepath.Join(t.TempDir(), "mod.jar")
+
+if err := os.WriteFile(destination+DownloadPartSuffix, content[:40000], 0o644); err != nil {
+t.Fatal(err)
+}
+
+if err := DownloadFile(testDownloadFile(server.URL, content), destination); err != nil {
+t.Fatalf("DownloadFile failed: %v", err)
+}
+
+data, _ := os.ReadFile(destination)
+if !bytes.Equal(data, content) {
+t.Fatal("resumed content differs")
+}
+if server.rangeHeaders.Load() != 1 {
+t.Errorf("expected a Range request, got %d", server.rangeHeaders.Load())
+}
+}
+
+func TestDownloadFileCompletePart(t *testing.T) {
+content := fingerprintTestData(1000)
+server := newTestDownloadServer(t, content)
+destination := filepath.Join(t.TempDir(), "mod.jar")
+
+if err := os.WriteFile(destination+DownloadPartSuffix, content, 0o644); err != nil {
+t.Fatal(err)
+}
+if err := DownloadFile(testDownloadFile(server.URL, content), destination); err != nil {
+t.Fatalf("DownloadFile failed: %v", err)
+}
+if server.requests.Load() != 0 {
+t.Errorf("expected no requests for a complete partial file, got %d", server.requests.Load())
+}
+}
+
+func TestDownloadFileHashMismatch(t *testing.T) {
+content := fingerprintTestData(5000)
+server := newTestDownloadServer(t, content)
+destination := filepath.Join(t.TempDir(), "mod.jar")
+
+file := testDownloadFile(server.URL, content)
+file.Hashes[0].Value = "0000000000000000000000000000000000000000"
+
+err := DownloadFile(file, destination)
+var mismatch *HashMismatchError
+if !errors.As(err, &mismatch) || mismatch.Check != "sha1" {
+t.Fatalf("expected sha1 HashMismatchError, got %v", err)
+}
+if !IsHashMismatch(err) {
+t.Error("IsHashMismatch returned false")
+}
+for _, path := range []string{destination, destination + DownloadPartSuffix} {
+if _, err := os.Stat(path); !os.IsNotExist(err) {
+t.Errorf("%s should not exist after a failed verification", path)
+}
+}
+}
+
+func TestDownloadFileErrorStatus(t *testing.T) {
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+w.WriteHeader(http.StatusNotFound)
+}))
+defer server.Close()
+destination := filepath.Join(t.TempDir(), "mod.jar")
+
+if err := DownloadFile(File{DownloadURL: server.URL}, destination); err == nil {
+t.Fatal("expected an error for a 404 response")
+}
+if _, err := os.Stat(destination); !os.IsNotExist(err) {
+t.Error("destination created for a failed download")
+}
+}
+
+func TestDownloadFileProgress(t *testing.T) {
+content := fingerprintTestData(200000)
+server := newTestDownloadServer(t, content)
+destination := filepath.Join(t.TempDir(), "mod.jar")
+
+events := make(chan DownloadProgress, 1000)
+options := DownloadOptions{OnProgress: ProgressChannel(events), ProgressInterval: time.Nanosecond}
+if err := DownloadFileWithOptions(testDownloadFile(server.URL, content), destination, options); err != nil {
+t.Fatalf("DownloadFileWithOptions failed: %v", err)
+}
+close(events)
+
+var last DownloadProgress
+count := 0
+for event := range events {
+if event.BytesDone < last.BytesDone {
+t.Errorf("progress went backwards: %d after %d", event.BytesDone, last.BytesDone)
+}
+last = event
+count++
+}
+if count < 2 {
+t.Errorf("expected several progress events, got %d", count)
+}
+if !last.Done || last.BytesDone != int64(len(content)) || last.TotalBytes != int64(len(content)) {
+t.Errorf("final event = %+v", last)
+}
+if last.Percent() != 100 {
+t.Errorf("final percent = %f", last.Percent())
+}
+}
+
+func TestDownloadFileFinalProgressEvent(t *testing.T) {
+content := fingerprintTestData(5000)
+server := newTestDownloadServer(t, content)
+
+tests := []struct {
+name    string
+modify  func(file *File)
+part    []byte
+wantErr bool
+}{
+{"complete part", func(file *File) {}, content, false},
+{"range not satisfiable", func(file *File) { file.FileLength = 0 }, content, false},
+{"hash mismatch", func(file *File) { file.Hashes[0].Value = strings.Repeat("0", 40) }, nil, true},
+{"error status", func(file *File) { file.DownloadURL = "http://127.0.0.1:0/missing" }, nil, true},
+{"manual download", func(file *File) { file.DownloadURL = "" }, nil, true},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+destination := filepath.Join(t.TempDir(), "mod.jar")
+if tt.part != nil {
+if err := os.WriteFile(destination+DownloadPartSuffix, tt.part, 0o644); err != nil {
+t.Fatal(err)
+}
+}
+file := testDownloadFile(server.URL, content)
+tt.modify(&file)
+
+var events []DownloadProgress
+options := DownloadOptions{OnProgress: func(p DownloadProgress) { events = append(events, p) }, ProgressInterval: time.Hour}
+err := DownloadFileWithOptions(file, destination, options)
+if (err != nil) != tt.wantErr {
+t.Fatalf("DownloadFileWithOptions = %v, wantErr %v", err, tt.wantErr)
+}
+
+if len(events) == 0 {
+t.Fatal("no final progress event")
+}
+for _, event := range events[:len(events)-1] {
+if event.Done {
+t.Errorf("Done sent before the final event: %+v", event)
+}
+}
+last := events[len(events)-1]
+if !last.Done || (last.Err != nil) != tt.wantErr || (tt.wantErr && !errors.Is(last.Err, err)) {
+t.Errorf("final event = %+v, download error %v", last, err)
+}
+if !tt.wantErr && last.BytesDone != int64(len(content)) {
+t.Errorf("final BytesDone = %d, want %d", last.BytesDone, len(content))
+}
+})
+}
+}
+
+func TestDownloadFileSharedRateLimiter(t *testing.T) {
+content := fingerprintTestData(75000)
+server := newTestDownloadServer(t, content)
+dir := t.TempDir()
+
+// Two downloads share 100KB/s; the bucket starts full, so 150KB takes at least ~0.5s
+limiter := NewRateLimiter(100000)
+start := time.Now()
+
+var wg sync.WaitGroup
+for _, name := range []string{"a.jar", "b.jar"} {
+wg.Add(1)
+go func(name string) {
+defer wg.Done()
+options := DownloadOptions{RateLimiter: limiter}
+if err := DownloadFileWithOptions(testDownloadFile(server.URL, content), filepath.Join(dir, name), options); err != nil {
+t.Errorf("DownloadFileWithOptions failed: %v", err)
+}
+}(name)
+}
+wg.Wait()
+
+if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+t.Errorf("rate limited downloads finished in %s", elapsed)
+}
+}
+
+func TestDownloadTo(t *testing.T) {
+content := fingerprintTestData(30000)
+server := newTestDownloadServer(t, content)
+
+var buf bytes.Buffer
+n, err := DownloadTo(&buf, testDownloadFile(server.URL, content), DownloadOptions{})
+if err != nil {
+t.Fatalf("DownloadTo failed: %v", err)
+}
+if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+t.Errorf("DownloadTo wrote %d bytes, content equal: %v", n, bytes.Equal(buf.Bytes(), content))
+}
+
+file := testDownloadFile(server.URL, content)
+file.Hashes[1].Value = "00000000000000000000000000000000"
+if _, err := DownloadTo(io.Discard, file, DownloadOptions{}); !IsHashMismatch(err) {
+t.Errorf("expected HashMismatchError from DownloadTo, got %v", err)
+}
+}
+
+func TestOpenDownload(t *testing.T) {
+content := fingerprintTestData(30000)
+server := newTestDownloadServer(t, content)
+
+file := testDownloadFile(server.URL, content)
+file.FileLength++
+
+stream, err := OpenDownload(file, DownloadOptions{})
+if err != nil {
+t.Fatalf("OpenDownload failed: %v", err)
+}
+defer stream.Close()
+
+data, err := io.ReadAll(stream)
+var mismatch *HashMismatchError
+if !errors.As(err, &mismatch) || mismatch.Check != "length" {
+t.Fatalf("expected length HashMismatchError, got %v", err)
+}
+if !bytes.Equal(data, content) {
+t.Error("streamed content differs")
+}
+if _, err := stream.Read(make([]byte, 1)); !IsHashMismatch(err) {
+t.Errorf("Read after failed verification returned %v", err)
+}
+
+if _, err := OpenDownload(File{ID: 1, ModID: 2}, DownloadOptions{}); !errors.Is(err, ErrDownloadURLUnavailable) {
+t.Errorf("expected ErrDownloadURLUnavailable, got %v", err)
+}
+}
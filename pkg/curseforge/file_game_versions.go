@@ -0,0 +1,225 @@
+package curseforge
+
+import (
+"regexp"
+"sort"
+"strconv"
+"strings"
+)
+
+// Game version type IDs CurseForge uses in SortableGameVersion for Minecraft
+// entries that are not Minecraft versions
+const (
+GameVersionTypeModLoader   = 68441
+GameVersionTypeEnvironment = 75208
+)
+
+// ModEnvironment is a side a mod runs on
+type ModEnvironment int
+
+const (
+EnvironmentClient ModEnvironment = 1
+EnvironmentServer ModEnvironment = 2
+)
+
+// String returns the string representation of ModEnvironment
+func (e ModEnvironment) String() string {
+switch e {
+case EnvironmentClient:
+return "client"
+case EnvironmentServer:
+return "server"
+default:
+return "unknown"
+}
+}
+
+// environmentFromString converts "Client" or "Server" to a ModEnvironment, or 0
+func environmentFromString(name string) ModEnvironment {
+switch strings.ToLower(strings.TrimSpace(name)) {
+case "client":
+return EnvironmentClient
+case "server":
+return EnvironmentServer
+default:
+return 0
+}
+}
+
+var javaVersionPattern = regexp.MustCompile(`(?i)^java\s*(\d+)$`)
+
+// gameVersionEntryKind is what an entry of File.GameVersions describes
+type gameVersionEntryKind int
+
+const (
+entryUnknown gameVersionEntryKind = iota
+entryMinecraft
+entryLoader
+entryEnvironment
+entryJava
+)
+
+// gameVersionEntry is a classified entry of a file's game versions
+type gameVersionEntry struct {
+kind        gameVersionEntryKind
+version     ParsedGameVersion
+loader      ModLoaderType
+environment ModEnvironment
+java        int
+}
+
+// gameVersionEntries classifies a file's SortableGameVersions and GameVersions
+// SortableGameVersions come first so their type IDs and release dates are
+// used; GameVersions entries they already cover are skipped
+func (f File) gameVersionEntries() []gameVersionEntry {
+var entries []gameVersionEntry
+seen := make(map[string]bool)
+for _, sortable := range f.SortableGameVersions {
+name := sortable.GameVersionName
+if name == "" {
+name = sortable.GameVersion
+}
+seen[strings.ToLower(name)] = true
+seen[strings.ToLower(sortable.GameVersion)] = true
+entries = append(entries, classifySortableGameVersion(sortable, name))
+}
+for _, name := range f.GameVersions {
+if seen[strings.ToLower(name)] {
+continue
+}
+seen[strings.ToLower(name)] = true
+entries = append(entries, classifyGameVersion(name))
+}
+return entries
+}
+
+// classifySortableGameVersion classifies an entry by its type ID, falling back to its name
+func classifySortableGameVersion(sortable SortableGameVersion, name string) gameVersionEntry {
+switch sortable.GameVersionTypeID {
+case GameVersionTypeModLoader:
+if loader := ModLoaderFromString(name); loader != ModLoaderAny {
+return gameVersionEntry{kind: entryLoader, loader: loader}
+}
+return gameVersionEntry{}
+case GameVersionTypeEnvironment:
+if environment := environmentFromString(name); environment != 0 {
+return gameVersionEntry{kind: entryEnvironment, environment: environment}
+}
+return gameVersionEntry{}
+}
+
+entry := classifyGameVersion(name)
+if entry.kind == entryMinecraft || entry.kind == entryUnknown {
+if version, err := ParseSortableGameVersion(sortable); err == nil {
+return gameVersionEntry{kind: entryMinecraft, version: version}
+}
+}
+return entry
+}
+
+// classifyGameVersion classifies a GameVersions entry by its name
+func classifyGameVersion(name string) gameVersionEntry {
+if loader := ModLoaderFromString(name); loader != ModLoaderAny {
+return gameVersionEntry{kind: entryLoader, loader: loader}
+}
+if environment := environmentFromString(name); environment != 0 {
+return gameVersionEntry{kind: entryEnvironment, environment: environment}
+}
+if match := javaVersionPattern.FindStringSubmatch(strings.TrimSpace(name)); match != nil {
+java, _ := strconv.Atoi(match[1])
+return gameVersionEntry{kind: entryJava, java: java}
+}
+if version, err := ParseGameVersion(name); err == nil {
+return gameVersionEntry{kind: entryMinecraft, version: version}
+}
+return gameVersionEntry{}
+}
+
+// MinecraftVersions returns the Minecraft versions a file supports
+// Loader, environment and Java entries are skipped; versions from
+// SortableGameVersions carry their release dates
+func (f File) MinecraftVersions() []ParsedGameVersion {
+var versions []ParsedGameVersion
+seen := make(map[string]bool)
+for _, entry := range f.gameVersionEntries() {
+if entry.kind == entryMinecraft && !seen[strings.ToLower(entry.version.Raw)] {
+seen[strings.ToLower(entry.version.Raw)] = true
+versions = append(versions, entry.version)
+}
+}
+return versions
+}
+
+// Loaders returns the mod loaders a file declares, in ascending order
+func (f File) Loaders() []ModLoaderType {
+var loaders []ModLoaderType
+for _, entry := range f.gameVersionEntries() {
+if entry.kind == entryLoader && !containsLoader(loaders, entry.loader) {
+loaders = append(loaders, entry.loader)
+}
+}
+sort.Slice(loaders, func(i, j int) bool { return loaders[i] < loaders[j] })
+return loaders
+}
+
+// containsLoader reports whether loaders contains loader
+func containsLoader(loaders []ModLoaderType, loader ModLoaderType) bool {
+for _, l := range loaders {
+if l == loader {
+return true
+}
+}
+return false
+}
+
+// Environments returns the sides a file declares, client first
+// Files that declare no environment return nil; see SupportsEnvironment
+func (f File) Environments() []ModEnvironment {
+var client, server bool
+for _, entry := range f.gameVersionEntries() {
+switch entry.environment {
+case EnvironmentClient:
+client = true
+case EnvironmentServer:
+server = true
+}
+}
+
+var environments []ModEnvironment
+if client {
+environments = append(environments, EnvironmentClient)
+}
+if server {
+environments = append(environments, EnvironmentServer)
+}
+return environments
+}
+
+// SupportsEnvironment reports whether a file runs on the given side
+// Files that declare no environment are assumed to run on both
+func (f File) SupportsEnvironment(environment ModEnvironment) bool {
+environments := f.Environments()
+if len(environments) == 0 {
+return true
+}
+for _, e := range environments {
+if e == environment {
+return true
+}
+}
+return false
+}
+
+// JavaVersions returns the Java major versions a file declares, in ascending order
+func (f File) JavaVersions() []int {
+var versions []int
+seen := make(map[int]bool)
+for _, entry := range f.gameVersionEntries() {
+if entry.kind == entryJava && !seen[entry.java] {
+seen[entry.java] = true
+versions = append(versions, entry.java)
+}
+}
+sort.Ints(versions)
+return versions
+}
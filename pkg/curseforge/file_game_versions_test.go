@@ -0,0 +1,109 @@
+package curseforge
+
+import (
+"fmt"
+"testing"
+)
+
+func TestFileGameVersionEntries(t *testing.T) {
+tests := []struct {
+name         string
+file         File
+versions     string
+loaders      []ModLoaderType
+environments []ModEnvironment
+java         []int
+}{
+{
+name:         "game versions only",
+file:         File{GameVersions: []string{"1.20.1", "Fabric", "quilt", "Client", "Server", "Java 17", "1.20"}},
+versions:     "[1.20.1 1.20]",
+loaders:      []ModLoaderType{ModLoaderFabric, ModLoaderQuilt},
+environments: []ModEnvironment{EnvironmentClient, EnvironmentServer},
+java:         []int{17},
+},
+{
+name: "sortable game versions with type IDs",
+file: File{
+GameVersions: []string{"1.20.1", "NeoForge", "Client", "Java 21", "Java 17"},
+SortableGameVersions: []SortableGameVersion{
+{GameVersionName: "1.20.1", GameVersionPadded: "0000000001.0000000020.0000000001", GameVersion: "1.20.1", GameVersionTypeID: 75125},
+{GameVersionName: "NeoForge", GameVersionPadded: "0", GameVersionTypeID: GameVersionTypeModLoader},
+{GameVersionName: "Client", GameVersionPadded: "0", GameVersionTypeID: GameVersionTypeEnvironment},
+},
+},
+versions:     "[1.20.1]",
+loaders:      []ModLoaderType{ModLoaderNeoForge},
+environments: []ModEnvironment{EnvironmentClient},
+java:         []int{17, 21},
+},
+{
+name:     "no loader or environment",
+file:     File{GameVersions: []string{"1.7.10", "Unknown Thing"}},
+versions: "[1.7.10]",
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+if got := fmt.Sprint(tt.file.MinecraftVersions()); got != tt.versions {
+t.Errorf("MinecraftVersions() = %s, want %s", got, tt.versions)
+}
+if got := fmt.Sprint(tt.file.ParsedGameVersions()); got != tt.versions {
+t.Errorf("ParsedGameVersions() = %s, want %s", got, tt.versions)
+}
+if got := tt.file.Loaders(); fmt.Sprint(got) != fmt.Sprint(tt.loaders) {
+t.Errorf("Loaders() = %v, want %v", got, tt.loaders)
+}
+if got := tt.file.Environments(); fmt.Sprint(got) != fmt.Sprint(tt.environments) {
+t.Errorf("Environments() = %v, want %v", got, tt.environments)
+}
+if got := tt.file.JavaVersions(); fmt.Sprint(got) != fmt.Sprint(tt.java) {
+t.Errorf("JavaVersions() = %v, want %v", got, tt.java)
+}
+})
+}
+}
+
+func TestFileSupportsEnvironment(t *testing.T) {
+clientOnly := File{GameVersions: []string{"1.20.1", "Client"}}
+if clientOnly.SupportsEnvironment(EnvironmentServer) || !clientOnly.SupportsEnvironment(EnvironmentClient) {
+t.Error("client-only file should only support the client")
+}
+
+unspecified := File{GameVersions: []string{"1.20.1"}}
+if !unspecified.SupportsEnvironment(EnvironmentServer) || !unspecified.SupportsEnvironment(EnvironmentClient) {
+t.Error("a file without environments should support both")
+}
+}
+
+func TestModLoaderFromString(t *testing.T) {
+tests := []struct {
+input    string
+expected ModLoaderType
+}{
+{input: "Forge", expected: ModLoaderForge},
+{input: "FORGE", expected: ModLoaderForge},
+{input: "MinecraftForge", expected: ModLoaderForge},
+{input: "NeoForge", expected: ModLoaderNeoForge},
+{input: "neo-forge", expected: ModLoaderNeoForge},
+{input: "NeoForged", expected: ModLoaderNeoForge},
+{input: "Fabric Loader", expected: ModLoaderFabric},
+{input: "quiltmc", expected: ModLoaderQuilt},
+{input: " LiteLoader ", expected: ModLoaderLiteLoader},
+{input: "Client", expected: ModLoaderAny},
+}
+
+for _, tt := range tests {
+t.Run(tt.input, func(t *testing.T) {
+if result := ModLoaderFromString(tt.input); result != tt.expected {
+t.Errorf("ModLoaderFromString(%q) = %v, want %v", tt.input, result, tt.expected)
+}
+})
+}
+
+file := File{GameVersions: []string{"1.20.1", "neoforge"}}
+if !file.HasModLoader(ModLoaderNeoForge) || file.HasModLoader(ModLoaderForge) {
+t.Error("HasModLoader should match case-insensitively without confusing Forge and NeoForge")
+}
+}
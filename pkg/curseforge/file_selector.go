@@ -0,0 +1,337 @@
+package curseforge
+
+import (
+"fmt"
+"sort"
+"time"
+)
+
+// FileConstraints describes which files of a mod are acceptable
+type FileConstraints struct {
+GameVersion string        // e.g. "1.20.1", empty for any version
+ModLoader   ModLoaderType // ModLoaderAny for any loader
+
+// ModLoaders, when set, only accepts files supporting at least one of the
+// loaders, e.g. for a file that declares both Forge and NeoForge
+ModLoaders []ModLoaderType
+
+// FallbackGameVersions are tried in order when no file supports
+// GameVersion, e.g. []string{"1.20", "1.19.4"}
+FallbackGameVersions []string
+
+// VersionConstraint accepts any matching game version, e.g. ">=1.19.2 <1.21",
+// after GameVersion and FallbackGameVersions
+VersionConstraint *GameVersionConstraint
+
+// MaxReleaseType is the least stable release type accepted, e.g.
+// ReleaseTypeBeta accepts releases and betas; zero accepts alphas too
+MaxReleaseType FileReleaseType
+
+ExcludeServerPacks  bool
+ExcludeEarlyAccess  bool
+ExcludeAlternatives bool // skip files exposed as alternatives of another file
+}
+
+// RejectionReason explains why SelectFile did not pick a file
+type RejectionReason string
+
+const (
+RejectedUnavailable RejectionReason = "unavailable"
+RejectedServerPack  RejectionReason = "serverPack"
+RejectedEarlyAccess RejectionReason = "earlyAccess"
+RejectedAlternative RejectionReason = "alternative"
+RejectedReleaseType RejectionReason = "releaseType"
+RejectedGameVersion RejectionReason = "gameVersion"
+RejectedModLoader   RejectionReason = "modLoader"
+RejectedSuperseded  RejectionReason = "superseded" // acceptable, but a better file was picked
+)
+
+// FileRejection records why a candidate file was not selected
+type FileRejection struct {
+File   File
+Reason RejectionReason
+Detail string
+}
+
+// FileSelection is the result of SelectFile
+type FileSelection struct {
+File        *File  // nil when no file satisfies the constraints
+GameVersion string // the game version File was matched for, possibly a fallback
+Rejected    []FileRejection
+}
+
+// Found reports whether a file was selected
+func (s *FileSelection) Found() bool {
+return s.File != nil
+}
+
+// Explain summarizes the selection for logs and error messages
+func (s *FileSelection) Explain() string {
+counts := make(map[RejectionReason]int)
+for _, rejection := range s.Rejected {
+counts[rejection.Reason]++
+}
+reasons := make([]string, 0, len(counts))
+for reason, count := range counts {
+reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+}
+sort.Strings(reasons)
+
+if s.File == nil {
+return fmt.Sprintf("no file selected, rejected: %v", reasons)
+}
+return fmt.Sprintf("selected file %d (%s) for %q, rejected: %v", s.File.ID, s.File.FileName, s.GameVersion, reasons)
+}
+
+// SelectFile picks the best file of a mod for the constraints
+//
+// Candidates come from the mod's LatestFilesIndexes, which name the newest file
+// per game version, loader and release type; files they reference that are not
+// in Mod.LatestFiles are fetched with GetFiles. When none of them is acceptable
+// every file of the mod is listed with GetAllModFiles.
+func SelectFile(server CurseForgeServer, mod Mod, constraints FileConstraints) (*FileSelection, error) {
+contextLogger.Trace(fmt.Sprintf("Selecting file for mod %d", mod.ID))
+
+candidates, err := indexedCandidates(server, mod, constraints)
+if err != nil {
+return nil, err
+}
+if selection := SelectFileFrom(candidates, constraints); selection.Found() {
+return selection, nil
+}
+
+files, err := GetAllModFiles(server, mod.ID, &GetModFilesRequest{ModLoaderType: constraints.ModLoader})
+if err != nil {
+return nil, fmt.Errorf("failed to list files of mod %d: %w", mod.ID, err)
+}
+return SelectFileFrom(files, constraints), nil
+}
+
+// SelectFileByModID fetches a mod and picks its best file, see SelectFile
+func SelectFileByModID(server CurseForgeServer, modID int, constraints FileConstraints) (*FileSelection, error) {
+mod, err := GetMod(server, modID)
+if err != nil {
+return nil, fmt.Errorf("failed to get mod %d: %w", modID, err)
+}
+return SelectFile(server, *mod, constraints)
+}
+
+// indexedCandidates collects the files referenced by matching latest file indexes
+func indexedCandidates(server CurseForgeServer, mod Mod, constraints FileConstraints) ([]File, error) {
+versions := constraints.gameVersions()
+indexes := mod.LatestFilesIndexes
+if !constraints.ExcludeEarlyAccess {
+indexes = append(append([]FileIndex{}, indexes...), mod.LatestEarlyAccessFilesIndexes...)
+}
+
+known := make(map[int]bool)
+candidates := append([]File{}, mod.LatestFiles...)
+for _, file := range candidates {
+known[file.ID] = true
+}
+
+var missing []int
+for _, index := range indexes {
+if known[index.FileID] || !constraints.acceptsIndex(index, versions) {
+continue
+}
+known[index.FileID] = true
+missing = append(missing, index.FileID)
+}
+
+if len(missing) > 0 {
+files, err := GetFiles(server, missing)
+if err != nil {
+return nil, fmt.Errorf("failed to get indexed files of mod %d: %w", mod.ID, err)
+}
+candidates = append(candidates, files...)
+}
+return candidates, nil
+}
+
+// acceptsIndex reports whether an index entry may point at an acceptable file
+func (c FileConstraints) acceptsIndex(index FileIndex, versions []string) bool {
+if c.MaxReleaseType != 0 && index.ReleaseType > c.MaxReleaseType {
+return false
+}
+if c.ModLoader != ModLoaderAny && index.ModLoader != ModLoaderAny && index.ModLoader != c.ModLoader {
+return false
+}
+if len(c.ModLoaders) > 0 && index.ModLoader != ModLoaderAny && !containsLoader(c.ModLoaders, index.ModLoader) {
+return false
+}
+if versions == nil && c.VersionConstraint == nil {
+return true
+}
+for _, version := range versions {
+if index.GameVersion == version {
+return true
+}
+}
+return c.VersionConstraint != nil && c.VersionConstraint.MatchesString(index.GameVersion)
+}
+
+// gameVersions returns the accepted game versions in order of preference
+// nil means any version
+func (c FileConstraints) gameVersions() []string {
+if c.GameVersion == "" {
+return nil
+}
+return append([]string{c.GameVersion}, c.FallbackGameVersions...)
+}
+
+// SelectFileFrom picks the best file among candidates without any API calls
+//
+// Files supporting GameVersion are preferred over those supporting a fallback
+// version, which are preferred over those only matching VersionConstraint;
+// among equally preferred files the newest wins. Every other
+// candidate is listed in Rejected with the first constraint it failed.
+func SelectFileFrom(candidates []File, constraints FileConstraints) *FileSelection {
+versions := constraints.gameVersions()
+selection := &FileSelection{}
+
+type accepted struct {
+file     File
+version  string
+priority int // index into versions, lower is better
+}
+var acceptable []accepted
+
+seen := make(map[int]bool)
+for _, file := range candidates {
+if seen[file.ID] {
+continue
+}
+seen[file.ID] = true
+
+if reason, detail := constraints.reject(file); reason != "" {
+selection.Rejected = append(selection.Rejected, FileRejection{File: file, Reason: reason, Detail: detail})
+continue
+}
+
+if versions == nil && constraints.VersionConstraint == nil {
+acceptable = append(acceptable, accepted{file: file})
+continue
+}
+version, priority := constraints.matchGameVersion(file, versions)
+if priority < 0 {
+selection.Rejected = append(selection.Rejected, FileRejection{
+File:   file,
+Reason: RejectedGameVersion,
+Detail: fmt.Sprintf("supports %v, want %s", file.GameVersions, constraints.describeVersions(versions)),
+})
+continue
+}
+acceptable = append(acceptable, accepted{file: file, version: version, priority: priority})
+}
+
+if len(acceptable) == 0 {
+return selection
+}
+
+sort.SliceStable(acceptable, func(i, j int) bool {
+a, b := acceptable[i], acceptable[j]
+if a.priority != b.priority {
+return a.priority < b.priority
+}
+if !a.file.FileDate.Equal(b.file.FileDate) {
+return a.file.FileDate.After(b.file.FileDate)
+}
+return a.file.ID > b.file.ID
+})
+
+best := acceptable[0]
+selection.File = &best.file
+selection.GameVersion = best.version
+for _, other := range acceptable[1:] {
+detail := fmt.Sprintf("older than file %d", best.file.ID)
+if other.priority != best.priority {
+detail = fmt.Sprintf("only supports fallback version %s", other.version)
+}
+selection.Rejected = append(selection.Rejected, FileRejection{File: other.file, Reason: RejectedSuperseded, Detail: detail})
+}
+return selection
+}
+
+// matchGameVersion finds the most preferred version a file supports
+// The priority is the index into versions, len(versions) for a match of
+// VersionConstraint, or -1 when the file supports none
+func (c FileConstraints) matchGameVersion(file File, versions []string) (string, int) {
+for i, version := range versions {
+if file.HasGameVersion(version) {
+return version, i
+}
+}
+if c.VersionConstraint == nil {
+return "", -1
+}
+
+matching := c.VersionConstraint.MatchingVersions(file)
+if len(matching) == 0 {
+return "", -1
+}
+highest := matching[0]
+for _, version := range matching[1:] {
+if result, ok := version.Compare(highest); ok && result > 0 {
+highest = version
+}
+}
+return highest.Raw, len(versions)
+}
+
+// describeVersions describes the accepted game versions for rejection details
+func (c FileConstraints) describeVersions(versions []string) string {
+description := fmt.Sprintf("one of %v", versions)
+switch {
+case c.VersionConstraint == nil:
+return description
+case versions == nil:
+return fmt.Sprintf("%q", c.VersionConstraint)
+default:
+return fmt.Sprintf("%s or %q", description, c.VersionConstraint)
+}
+}
+
+// reject checks the constraints other than the game version
+func (c FileConstraints) reject(file File) (RejectionReason, string) {
+if !file.IsAvailable {
+return RejectedUnavailable, "file is not available"
+}
+if c.ExcludeServerPacks && file.IsServerPack != nil && *file.IsServerPack {
+return RejectedServerPack, "file is a server pack"
+}
+if c.ExcludeEarlyAccess && isEarlyAccess(file, time.Now()) {
+return RejectedEarlyAccess, "file is early access content"
+}
+if c.ExcludeAlternatives && file.ExposeAsAlternative != nil && *file.ExposeAsAlternative {
+return RejectedAlternative, "file is an alternative of another file"
+}
+if c.MaxReleaseType != 0 && file.ReleaseType > c.MaxReleaseType {
+return RejectedReleaseType, fmt.Sprintf("%s is less stable than %s", file.ReleaseType, c.MaxReleaseType)
+}
+if c.ModLoader != ModLoaderAny && !file.HasModLoader(c.ModLoader) {
+return RejectedModLoader, fmt.Sprintf("does not support %s", c.ModLoader)
+}
+if len(c.ModLoaders) > 0 && !supportsAnyLoader(file, c.ModLoaders) {
+return RejectedModLoader, fmt.Sprintf("supports none of %v", c.ModLoaders)
+}
+return "", ""
+}
+
+// supportsAnyLoader reports whether file supports at least one of loaders
+func supportsAnyLoader(file File, loaders []ModLoaderType) bool {
+for _, loader := range loaders {
+if file.HasModLoader(loader) {
+return true
+}
+}
+return false
+}
+
+// isEarlyAccess reports whether a file is still early access content at now
+func isEarlyAccess(file File, now time.Time) bool {
+if file.IsEarlyAccessContent == nil || !*file.IsEarlyAccessContent {
+return false
+}
+return file.EarlyAccessEndDate == nil || now.Before(*file.EarlyAccessEndDate)
+}
@@ -0,0 +1,197 @@
+package curseforge
+
+import (
+"encoding/json"
+"net/http"
+"net/http/httptest"
+"testing"
+"time"
+)
+
+func selectorTestFile(id int, day int, releaseType FileReleaseType, versions ...string) File {
+return File{
+ID:           id,
+ModID:        1,
+FileName:     "mod.jar",
+IsAvailable:  true,
+ReleaseType:  releaseType,
+FileDate:     time.Date(2023, 6, day, 0, 0, 0, 0, time.UTC),
+GameVersions: versions,
+}
+}
+
+func TestSelectFileFrom(t *testing.T) {
+yes := true
+future := time.Now().Add(24 * time.Hour)
+past := time.Now().Add(-24 * time.Hour)
+
+serverPack := selectorTestFile(5, 20, ReleaseTypeRelease, "1.20.1", "Forge")
+serverPack.IsServerPack = &yes
+earlyAccess := selectorTestFile(6, 21, ReleaseTypeRelease, "1.20.1", "Forge")
+earlyAccess.IsEarlyAccessContent, earlyAccess.EarlyAccessEndDate = &yes, &future
+endedEarlyAccess := selectorTestFile(7, 2, ReleaseTypeRelease, "1.20.1", "Forge")
+endedEarlyAccess.IsEarlyAccessContent, endedEarlyAccess.EarlyAccessEndDate = &yes, &past
+alternative := selectorTestFile(8, 22, ReleaseTypeRelease, "1.20.1", "Forge")
+alternative.ExposeAsAlternative = &yes
+unavailable := selectorTestFile(9, 23, ReleaseTypeRelease, "1.20.1", "Forge")
+unavailable.IsAvailable = false
+
+candidates := []File{
+selectorTestFile(1, 1, ReleaseTypeRelease, "1.20.1", "Forge"),
+selectorTestFile(2, 10, ReleaseTypeAlpha, "1.20.1", "Forge"),
+selectorTestFile(3, 11, ReleaseTypeRelease, "1.20.1", "Fabric"),
+selectorTestFile(4, 12, ReleaseTypeRelease, "1.19.2", "Forge"),
+serverPack, earlyAccess, endedEarlyAccess, alternative, unavailable,
+}
+
+tests := []struct {
+name        string
+candidates  []File
+constraints FileConstraints
+expected    int
+version     string
+rejections  map[int]RejectionReason
+}{
+{
+name:       "newest wins without constraints",
+candidates: candidates,
+expected:   8,
+rejections: map[int]RejectionReason{9: RejectedUnavailable, 1: RejectedSuperseded},
+},
+{
+name:       "all constraints",
+candidates: candidates,
+constraints: FileConstraints{
+GameVersion:         "1.20.1",
+ModLoader:           ModLoaderForge,
+MaxReleaseType:      ReleaseTypeBeta,
+ExcludeServerPacks:  true,
+ExcludeEarlyAccess:  true,
+ExcludeAlternatives: true,
+},
+expected: 7,
+version:  "1.20.1",
+rejections: map[int]RejectionReason{
+1: RejectedSuperseded,
+2: RejectedReleaseType,
+3: RejectedModLoader,
+4: RejectedGameVersion,
+5: RejectedServerPack,
+6: RejectedEarlyAccess,
+8: RejectedAlternative,
+9: RejectedUnavailable,
+},
+},
+{
+name:        "fallback version",
+candidates:  candidates[:4],
+constraints: FileConstraints{GameVersion: "1.19.4", FallbackGameVersions: []string{"1.19.3", "1.19.2"}, ModLoader: ModLoaderForge},
+expected:    4,
+version:     "1.19.2",
+},
+{
+name:        "preferred version beats newer fallback",
+candidates:  []File{selectorTestFile(1, 1, ReleaseTypeRelease, "1.20.1"), selectorTestFile(2, 9, ReleaseTypeRelease, "1.20")},
+constraints: FileConstraints{GameVersion: "1.20.1", FallbackGameVersions: []string{"1.20"}},
+expected:    1,
+version:     "1.20.1",
+rejections:  map[int]RejectionReason{2: RejectedSuperseded},
+},
+{
+name:        "nothing matches",
+candidates:  candidates[:4],
+constraints: FileConstraints{GameVersion: "1.18.2"},
+rejections:  map[int]RejectionReason{1: RejectedGameVersion, 4: RejectedGameVersion},
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+selection := SelectFileFrom(tt.candidates, tt.constraints)
+
+got := 0
+if selection.Found() {
+got = selection.File.ID
+}
+if got != tt.expected {
+t.Fatalf("selected file %d, want %d: %s", got, tt.expected, selection.Explain())
+}
+if selection.GameVersion != tt.version {
+t.Errorf("GameVersion = %q, want %q", selection.GameVersion, tt.version)
+}
+expectedRejections := len(tt.candidates)
+if got != 0 {
+expectedRejections--
+}
+if len(selection.Rejected) != expectedRejections {
+t.Errorf("rejected %d of %d candidates", len(selection.Rejected), len(tt.candidates))
+}
+
+reasons := make(map[int]RejectionReason)
+for _, rejection := range selection.Rejected {
+reasons[rejection.File.ID] = rejection.Reason
+}
+for id, reason := range tt.rejections {
+if reasons[id] != reason {
+t.Errorf("file %d rejected as %q, want %q", id, reasons[id], reason)
+}
+}
+})
+}
+}
+
+func TestSelectFile(t *testing.T) {
+var listed bool
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch r.URL.Path {
+case "/v1/mods/files":
+var request GetFilesRequest
+json.NewDecoder(r.Body).Decode(&request)
+if len(request.FileIDs) != 1 || request.FileIDs[0] != 11 {
+t.Errorf("fetched indexed files %v, want [11]", request.FileIDs)
+}
+json.NewEncoder(w).Encode(Response[[]File]{Data: []File{selectorTestFile(11, 2, ReleaseTypeRelease, "1.20.1", "Forge")}})
+case "/v1/mods/1/files":
+listed = true
+json.NewEncoder(w).Encode(PaginatedResponse[[]File]{
+Data:       []File{selectorTestFile(20, 1, ReleaseTypeRelease, "1.18.2", "Forge")},
+Pagination: Pagination{ResultCount: 1, TotalCount: 1},
+})
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+defer server.Close()
+cf := NewServerWithURL("key", server.URL)
+
+mod := Mod{
+ID:          1,
+LatestFiles: []File{selectorTestFile(10, 1, ReleaseTypeBeta, "1.20.1", "Forge")},
+LatestFilesIndexes: []FileIndex{
+{GameVersion: "1.20.1", FileID: 10, ReleaseType: ReleaseTypeBeta, ModLoader: ModLoaderForge},
+{GameVersion: "1.20.1", FileID: 11, ReleaseType: ReleaseTypeRelease, ModLoader: ModLoaderForge},
+{GameVersion: "1.20.1", FileID: 12, ReleaseType: ReleaseTypeRelease, ModLoader: ModLoaderFabric},
+{GameVersion: "1.19.2", FileID: 13, ReleaseType: ReleaseTypeRelease, ModLoader: ModLoaderForge},
+},
+}
+
+selection, err := SelectFile(cf, mod, FileConstraints{GameVersion: "1.20.1", ModLoader: ModLoaderForge})
+if err != nil {
+t.Fatalf("SelectFile failed: %v", err)
+}
+if !selection.Found() || selection.File.ID != 11 {
+t.Fatalf("selected %s, want file 11", selection.Explain())
+}
+if listed {
+t.Error("all files should not be listed when an indexed file matches")
+}
+
+selection, err = SelectFile(cf, Mod{ID: 1}, FileConstraints{GameVersion: "1.18.2"})
+if err != nil {
+t.Fatalf("SelectFile failed: %v", err)
+}
+if !listed || !selection.Found() || selection.File.ID != 20 {
+t.Errorf("expected fallback to listing all files, got %s", selection.Explain())
+}
+}
@@ -0,0 +1,487 @@
+package curseforge
+
+import (
+"errors"
+"io"
+"io/fs"
+"os"
+"path"
+"path/filepath"
+"sort"
+"strings"
+"sync"
+"time"
+)
+
+// WritableFS is the filesystem download and install helpers write to
+// OSFS targets the real filesystem; MemFS keeps everything in memory, which
+// lets tests assert the exact tree an installer produces without touching disk
+type WritableFS interface {
+OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error)
+MkdirAll(path string, perm fs.FileMode) error
+Rename(oldpath string, newpath string) error
+Remove(name string) error
+Stat(name string) (fs.FileInfo, error)
+
+// Join and Dir build paths with the separator of the filesystem
+Join(elem ...string) string
+Dir(name string) string
+}
+
+// WritableFile is an open file of a WritableFS; *os.File implements it
+type WritableFile interface {
+io.Reader
+io.Writer
+io.Seeker
+io.Closer
+Stat() (fs.FileInfo, error)
+Truncate(size int64) error
+Sync() error
+}
+
+// OSFS implements WritableFS with the os package using native paths
+type OSFS struct{}
+
+func (OSFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+return os.OpenFile(name, flag, perm)
+}
+
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error {
+return os.MkdirAll(path, perm)
+}
+
+func (OSFS) Rename(oldpath string, newpath string) error {
+return os.Rename(oldpath, newpath)
+}
+
+func (OSFS) Remove(name string) error {
+return os.Remove(name)
+}
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+return os.Stat(name)
+}
+
+func (OSFS) Join(elem ...string) string {
+return filepath.Join(elem...)
+}
+
+func (OSFS) Dir(name string) string {
+return filepath.Dir(name)
+}
+
+// orOSFS returns fsys, or OSFS when it is nil
+func orOSFS(fsys WritableFS) WritableFS {
+if fsys == nil {
+return OSFS{}
+}
+return fsys
+}
+
+// dirOf returns the parent directory of name for the given filesystem, OSFS when nil
+func dirOf(fsys WritableFS, name string) string {
+return orOSFS(fsys).Dir(name)
+}
+
+// joinPath joins path elements using the separator of the given filesystem, OSFS when nil
+func joinPath(fsys WritableFS, elem ...string) string {
+return orOSFS(fsys).Join(elem...)
+}
+
+// OpenFS opens a file of a WritableFS for reading
+func OpenFS(fsys WritableFS, name string) (WritableFile, error) {
+return orOSFS(fsys).OpenFile(name, os.O_RDONLY, 0)
+}
+
+// ReadFileFS reads a whole file from a WritableFS
+func ReadFileFS(fsys WritableFS, name string) ([]byte, error) {
+file, err := OpenFS(fsys, name)
+if err != nil {
+return nil, err
+}
+defer file.Close()
+return io.ReadAll(file)
+}
+
+// WriteFileFS atomically writes data to name, creating parent directories
+func WriteFileFS(fsys WritableFS, name string, data []byte, perm fs.FileMode) error {
+return writeFileFS(fsys, name, perm, func(w io.Writer) error {
+_, err := w.Write(data)
+return err
+})
+}
+
+// writeFileFS writes name through a temporary file renamed into place
+func writeFileFS(fsys WritableFS, name string, perm fs.FileMode, write func(w io.Writer) error) error {
+fsys = orOSFS(fsys)
+if err := fsys.MkdirAll(dirOf(fsys, name), 0o755); err != nil {
+return err
+}
+
+tmpPath := name + DownloadPartSuffix
+out, err := fsys.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+if err != nil {
+return err
+}
+if err := write(out); err != nil {
+out.Close()
+fsys.Remove(tmpPath)
+return err
+}
+if err := out.Close(); err != nil {
+fsys.Remove(tmpPath)
+return err
+}
+return fsys.Rename(tmpPath, name)
+}
+
+// copyFileFS copies a file between (possibly different) filesystems atomically
+func copyFileFS(srcFS WritableFS, source string, dstFS WritableFS, destination string) error {
+in, err := OpenFS(srcFS, source)
+if err != nil {
+return err
+}
+defer in.Close()
+
+return writeFileFS(dstFS, destination, 0o644, func(w io.Writer) error {
+_, err := io.Copy(w, in)
+return err
+})
+}
+
+// MemFS is an in-memory WritableFS
+// Paths use forward slashes; a leading slash and "." elements are ignored, so
+// "/a/b", "a/b" and "a/./b" name the same file
+type MemFS struct {
+mu    sync.RWMutex
+files map[string]*memNode
+dirs  map[string]bool
+}
+
+// memNode holds the content of an in-memory file
+type memNode struct {
+mu      sync.RWMutex
+data    []byte
+mode    fs.FileMode
+modTime time.Time
+}
+
+// resize sets the length of the content, zeroing the bytes it grows by
+// Capacity at least doubles when it runs out, so sequential writes stay linear
+func (n *memNode) resize(size int64) {
+length := int64(len(n.data))
+if size <= length {
+n.data = n.data[:size]
+return
+}
+if size > int64(cap(n.data)) {
+capacity := 2 * int64(cap(n.data))
+if capacity < size {
+capacity = size
+}
+grown := make([]byte, length, capacity)
+copy(grown, n.data)
+n.data = grown
+}
+n.data = n.data[:size]
+tail := n.data[length:]
+for i := range tail {
+tail[i] = 0
+}
+}
+
+// NewMemFS creates an empty in-memory filesystem
+func NewMemFS() *MemFS {
+return &MemFS{
+files: make(map[string]*memNode),
+dirs:  map[string]bool{".": true},
+}
+}
+
+// memPath normalizes a path for MemFS
+func memPath(name string) string {
+return path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+}
+
+func (m *MemFS) Join(elem ...string) string {
+return path.Join(elem...)
+}
+
+func (m *MemFS) Dir(name string) string {
+return path.Dir(filepath.ToSlash(name))
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+name = memPath(name)
+
+m.mu.Lock()
+defer m.mu.Unlock()
+
+if m.dirs[name] {
+return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+}
+
+node, exists := m.files[name]
+switch {
+case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+case !exists && flag&os.O_CREATE == 0:
+return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+case !exists:
+if !m.dirs[path.Dir(name)] {
+return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+node = &memNode{mode: perm, modTime: time.Now()}
+m.files[name] = node
+}
+
+writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+if writable && flag&os.O_TRUNC != 0 {
+node.mu.Lock()
+node.data = nil
+node.modTime = time.Now()
+node.mu.Unlock()
+}
+
+return &memFile{
+name:     name,
+node:     node,
+readable: flag&os.O_WRONLY == 0,
+writable: writable,
+append:   flag&os.O_APPEND != 0,
+}, nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+name = memPath(name)
+
+m.mu.Lock()
+defer m.mu.Unlock()
+
+for dir := name; ; dir = path.Dir(dir) {
+if _, isFile := m.files[dir]; isFile {
+return &fs.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+}
+m.dirs[dir] = true
+if dir == "." {
+return nil
+}
+}
+}
+
+func (m *MemFS) Rename(oldpath string, newpath string) error {
+oldpath, newpath = memPath(oldpath), memPath(newpath)
+
+m.mu.Lock()
+defer m.mu.Unlock()
+
+node, ok := m.files[oldpath]
+if !ok {
+return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+}
+if !m.dirs[path.Dir(newpath)] || m.dirs[newpath] {
+return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+}
+delete(m.files, oldpath)
+m.files[newpath] = node
+return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+name = memPath(name)
+
+m.mu.Lock()
+defer m.mu.Unlock()
+
+if _, ok := m.files[name]; ok {
+delete(m.files, name)
+return nil
+}
+if !m.dirs[name] {
+return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+prefix := name + "/"
+for other := range m.files {
+if strings.HasPrefix(other, prefix) {
+return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+}
+}
+for other := range m.dirs {
+if strings.HasPrefix(other, prefix) {
+return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+}
+}
+delete(m.dirs, name)
+return nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+name = memPath(name)
+
+m.mu.RLock()
+defer m.mu.RUnlock()
+
+if node, ok := m.files[name]; ok {
+return node.info(name), nil
+}
+if m.dirs[name] {
+return memFileInfo{name: path.Base(name), mode: fs.ModeDir | 0o755}, nil
+}
+return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Files returns the sorted paths of every file in the filesystem
+func (m *MemFS) Files() []string {
+m.mu.RLock()
+defer m.mu.RUnlock()
+
+names := make([]string, 0, len(m.files))
+for name := range m.files {
+names = append(names, name)
+}
+sort.Strings(names)
+return names
+}
+
+// ReadFile returns the content of a file
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+return ReadFileFS(m, name)
+}
+
+// WriteFile creates or replaces a file, creating parent directories
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+return WriteFileFS(m, name, data, perm)
+}
+
+// info returns the FileInfo of a node
+func (n *memNode) info(name string) fs.FileInfo {
+n.mu.RLock()
+defer n.mu.RUnlock()
+return memFileInfo{name: path.Base(name), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// memFile is an open handle on a memNode
+type memFile struct {
+name     string
+node     *memNode
+offset   int64
+readable bool
+writable bool
+append   bool
+closed   bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+if f.closed {
+return 0, fs.ErrClosed
+}
+if !f.readable {
+return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrPermission}
+}
+
+f.node.mu.RLock()
+defer f.node.mu.RUnlock()
+
+if f.offset >= int64(len(f.node.data)) {
+return 0, io.EOF
+}
+n := copy(p, f.node.data[f.offset:])
+f.offset += int64(n)
+return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+if f.closed {
+return 0, fs.ErrClosed
+}
+if !f.writable {
+return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+}
+
+f.node.mu.Lock()
+defer f.node.mu.Unlock()
+
+if f.append {
+f.offset = int64(len(f.node.data))
+}
+end := f.offset + int64(len(p))
+if end > int64(len(f.node.data)) {
+f.node.resize(end)
+}
+copy(f.node.data[f.offset:], p)
+f.offset = end
+f.node.modTime = time.Now()
+return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+if f.closed {
+return 0, fs.ErrClosed
+}
+
+f.node.mu.RLock()
+size := int64(len(f.node.data))
+f.node.mu.RUnlock()
+
+switch whence {
+case io.SeekStart:
+case io.SeekCurrent:
+offset += f.offset
+case io.SeekEnd:
+offset += size
+default:
+return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+}
+if offset < 0 {
+return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+}
+f.offset = offset
+return offset, nil
+}
+
+func (f *memFile) Close() error {
+if f.closed {
+return fs.ErrClosed
+}
+f.closed = true
+return nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+return f.node.info(f.name), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+if !f.writable {
+return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrPermission}
+}
+if size < 0 {
+return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+}
+
+f.node.mu.Lock()
+defer f.node.mu.Unlock()
+
+f.node.resize(size)
+f.node.modTime = time.Now()
+return nil
+}
+
+func (f *memFile) Sync() error {
+return nil
+}
+
+// memFileInfo implements fs.FileInfo for MemFS
+type memFileInfo struct {
+name    string
+size    int64
+mode    fs.FileMode
+modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,144 @@
+package curseforge
+
+import (
+"bytes"
+"errors"
+"fmt"
+"io"
+"io/fs"
+"os"
+"path/filepath"
+"strings"
+"testing"
+)
+
+func TestMemFS(t *testing.T) {
+mem := NewMemFS()
+
+if _, err := mem.OpenFile("mods/a.jar", os.O_WRONLY|os.O_CREATE, 0o644); !errors.Is(err, fs.ErrNotExist) {
+t.Errorf("creating a file without its directory returned %v", err)
+}
+if err := mem.MkdirAll("/instance/mods", 0o755); err != nil {
+t.Fatal(err)
+}
+
+file, err := mem.OpenFile("instance/mods/a.jar", os.O_RDWR|os.O_CREATE, 0o644)
+if err != nil {
+t.Fatalf("OpenFile failed: %v", err)
+}
+file.Write([]byte("hello world"))
+file.Truncate(5)
+file.Seek(0, io.SeekStart)
+data, _ := io.ReadAll(file)
+file.Close()
+if string(data) != "hello" {
+t.Errorf("content = %q, want %q", data, "hello")
+}
+
+if err := mem.Rename("instance/mods/a.jar", "/instance/mods/b.jar"); err != nil {
+t.Fatalf("Rename failed: %v", err)
+}
+if err := mem.Remove("instance/mods"); err == nil {
+t.Error("removed a non-empty directory")
+}
+if info, err := mem.Stat("instance/mods/b.jar"); err != nil || info.Size() != 5 {
+t.Errorf("Stat = %v, %v", info, err)
+}
+if err := mem.WriteFile("instance/config/a.toml", []byte("x = 1"), 0o644); err != nil {
+t.Fatal(err)
+}
+
+expected := "[instance/config/a.toml instance/mods/b.jar]"
+if files := fmt.Sprint(mem.Files()); files != expected {
+t.Errorf("Files = %s, want %s", files, expected)
+}
+}
+
+func TestDownloadFileToMemFS(t *testing.T) {
+content := fingerprintTestData(50000)
+server := newTestDownloadServer(t, content)
+mem := NewMemFS()
+
+// A partial download in the in-memory tree is resumed as on disk
+mem.WriteFile("instance/mods/mod.jar"+DownloadPartSuffix, content[:20000], 0o644)
+
+file := testDownloadFile(server.URL, content)
+options := DownloadManagerOptions{Download: DownloadOptions{FS: mem, VerifyFingerprint: true}}
+summary := DownloadFiles([]DownloadRequest{
+{File: file, Destination: "instance/mods/mod.jar"},
+{File: file, Destination: "backup/mod.jar"},
+}, options)
+if err := summary.Err(); err != nil {
+t.Fatalf("DownloadFiles failed: %v", err)
+}
+
+expected := "[backup/mod.jar instance/mods/mod.jar]"
+if files := fmt.Sprint(mem.Files()); files != expected {
+t.Errorf("Files = %s, want %s", files, expected)
+}
+for _, name := range mem.Files() {
+if data, _ := mem.ReadFile(name); !bytes.Equal(data, content) {
+t.Errorf("%s content differs", name)
+}
+}
+if server.rangeHeaders.Load() != 1 {
+t.Errorf("expected the partial download to be resumed, got %d range requests", server.rangeHeaders.Load())
+}
+}
+
+func TestMemFSGrowth(t *testing.T) {
+mem := NewMemFS()
+file, err := mem.OpenFile("a.bin", os.O_RDWR|os.O_CREATE, 0o644)
+if err != nil {
+t.Fatal(err)
+}
+defer file.Close()
+
+content := fingerprintTestData(1 << 20)
+if _, err := io.Copy(file, bytes.NewReader(content)); err != nil {
+t.Fatal(err)
+}
+
+if err := file.Truncate(-1); !errors.Is(err, fs.ErrInvalid) {
+t.Errorf("Truncate(-1) = %v, want fs.ErrInvalid", err)
+}
+
+// Bytes revealed by growing after a shrink or by writing past the end read as zero
+file.Truncate(4)
+file.Truncate(8)
+file.Seek(12, io.SeekStart)
+file.Write([]byte("end"))
+file.Seek(0, io.SeekStart)
+data, _ := io.ReadAll(file)
+expected := append(append([]byte{}, content[:4]...), make([]byte, 8)...)
+expected = append(expected, "end"...)
+if !bytes.Equal(data, expected) {
+t.Errorf("content = %v, want %v", data, expected)
+}
+}
+
+// backslashFS is a WritableFS with its own path separator
+type backslashFS struct {
+*MemFS
+}
+
+func (backslashFS) Join(elem ...string) string {
+return strings.Join(elem, `\`)
+}
+
+func (backslashFS) Dir(name string) string {
+return name[:strings.LastIndex(name, `\`)]
+}
+
+func TestWritableFSPaths(t *testing.T) {
+fsys := backslashFS{NewMemFS()}
+if got := joinPath(fsys, "mods", "a.jar"); got != `mods\a.jar` {
+t.Errorf("joinPath = %q", got)
+}
+if got := dirOf(fsys, `mods\a.jar`); got != "mods" {
+t.Errorf("dirOf = %q", got)
+}
+if got := joinPath(nil, "mods", "a.jar"); got != filepath.Join("mods", "a.jar") {
+t.Errorf("joinPath with nil = %q", got)
+}
+}
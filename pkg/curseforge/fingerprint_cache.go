@@ -0,0 +1,289 @@
+package curseforge
+
+import (
+"encoding/json"
+"errors"
+"fmt"
+"os"
+"path/filepath"
+"sync"
+"time"
+)
+
+// fingerprintCacheVersion is bumped whenever the on-disk format changes
+const fingerprintCacheVersion = 1
+
+// Lock file timings used by FingerprintCache.Save
+const (
+fingerprintCacheLockTimeout = 10 * time.Second
+fingerprintCacheLockStale   = time.Minute
+fingerprintCacheLockRetry   = 10 * time.Millisecond
+)
+
+// FingerprintCacheEntry holds the cached hashes of a file
+// An entry is only valid while the file's size and modification time are unchanged
+type FingerprintCacheEntry struct {
+Size        int64     `json:"size"`
+ModTime     time.Time `json:"modTime"`
+Fingerprint int64     `json:"fingerprint"`
+Sha1        string    `json:"sha1"`
+}
+
+// fingerprintCacheFile is the on-disk representation of a FingerprintCache
+type fingerprintCacheFile struct {
+Version int                              `json:"version"`
+Entries map[string]FingerprintCacheEntry `json:"entries"`
+}
+
+// FingerprintCache caches file fingerprints keyed by absolute path, size and
+// modification time so unchanged files are not rehashed on every scan
+// It is safe for concurrent use; Save merges with entries written by other
+// processes sharing the same cache file, holding a lock file while it does
+type FingerprintCache struct {
+path    string
+mu      sync.RWMutex
+entries map[string]FingerprintCacheEntry
+deleted map[string]bool // keys removed since the last Save, not merged back from disk
+}
+
+// NewFingerprintCache creates an in-memory cache that is not persisted
+func NewFingerprintCache() *FingerprintCache {
+return &FingerprintCache{entries: make(map[string]FingerprintCacheEntry), deleted: make(map[string]bool)}
+}
+
+// OpenFingerprintCache loads the cache stored at path, starting empty if the
+// file does not exist yet; Save writes back to the same path
+func OpenFingerprintCache(path string) (*FingerprintCache, error) {
+entries, err := readFingerprintCacheFile(path)
+if err != nil {
+return nil, err
+}
+return &FingerprintCache{path: path, entries: entries, deleted: make(map[string]bool)}, nil
+}
+
+// readFingerprintCacheFile reads cache entries, ignoring missing or outdated files
+func readFingerprintCacheFile(path string) (map[string]FingerprintCacheEntry, error) {
+entries := make(map[string]FingerprintCacheEntry)
+
+data, err := os.ReadFile(path)
+if errors.Is(err, os.ErrNotExist) {
+return entries, nil
+}
+if err != nil {
+return nil, fmt.Errorf("failed to read fingerprint cache: %w", err)
+}
+
+var cacheFile fingerprintCacheFile
+if err := json.Unmarshal(data, &cacheFile); err != nil {
+return nil, fmt.Errorf("failed to unmarshal fingerprint cache: %w", err)
+}
+if cacheFile.Version != fingerprintCacheVersion {
+contextLogger.Trace(fmt.Sprintf("discarding fingerprint cache with version %d", cacheFile.Version))
+return entries, nil
+}
+for key, entry := range cacheFile.Entries {
+entries[key] = entry
+}
+return entries, nil
+}
+
+// cacheKey returns the absolute path used as cache key
+func cacheKey(filePath string) (string, error) {
+return filepath.Abs(filePath)
+}
+
+// Lookup returns the cached entry for a file if it is still valid
+func (c *FingerprintCache) Lookup(filePath string) (FingerprintCacheEntry, bool) {
+key, err := cacheKey(filePath)
+if err != nil {
+return FingerprintCacheEntry{}, false
+}
+info, err := os.Stat(key)
+if err != nil {
+return FingerprintCacheEntry{}, false
+}
+
+c.mu.RLock()
+entry, ok := c.entries[key]
+c.mu.RUnlock()
+
+if !ok || !entry.matches(info) {
+return FingerprintCacheEntry{}, false
+}
+return entry, true
+}
+
+// matches reports whether the entry still describes the file
+func (e FingerprintCacheEntry) matches(info os.FileInfo) bool {
+return e.Size == info.Size() && e.ModTime.Equal(info.ModTime())
+}
+
+// Hashes returns the cached entry for a file, hashing it and updating the
+// cache if the file is new or has changed
+func (c *FingerprintCache) Hashes(filePath string) (FingerprintCacheEntry, error) {
+if entry, ok := c.Lookup(filePath); ok {
+return entry, nil
+}
+
+key, err := cacheKey(filePath)
+if err != nil {
+return FingerprintCacheEntry{}, err
+}
+before, err := os.Stat(key)
+if err != nil {
+return FingerprintCacheEntry{}, err
+}
+
+hashes, err := ComputeFileHashes(key)
+if err != nil {
+return FingerprintCacheEntry{}, err
+}
+
+entry := FingerprintCacheEntry{
+Size:        before.Size(),
+ModTime:     before.ModTime(),
+Fingerprint: hashes.Fingerprint,
+Sha1:        hashes.Sha1,
+}
+
+// Only cache the result if the file did not change while it was hashed
+if after, err := os.Stat(key); err == nil && entry.matches(after) && hashes.Size == after.Size() {
+c.mu.Lock()
+c.entries[key] = entry
+delete(c.deleted, key)
+c.mu.Unlock()
+}
+return entry, nil
+}
+
+// Fingerprint returns the fingerprint of a file, using the cache when valid
+// It has the same signature as ComputeFileFingerprint
+func (c *FingerprintCache) Fingerprint(filePath string) (int64, error) {
+entry, err := c.Hashes(filePath)
+if err != nil {
+return 0, err
+}
+return entry.Fingerprint, nil
+}
+
+// Invalidate removes the cached entry for a file
+func (c *FingerprintCache) Invalidate(filePath string) {
+key, err := cacheKey(filePath)
+if err != nil {
+return
+}
+c.mu.Lock()
+delete(c.entries, key)
+c.deleted[key] = true
+c.mu.Unlock()
+}
+
+// Prune removes entries for files that no longer exist or have changed and
+// returns the number of entries removed
+func (c *FingerprintCache) Prune() int {
+c.mu.Lock()
+defer c.mu.Unlock()
+
+removed := 0
+for key, entry := range c.entries {
+info, err := os.Stat(key)
+if err != nil || !entry.matches(info) {
+delete(c.entries, key)
+c.deleted[key] = true
+removed++
+}
+}
+return removed
+}
+
+// Len returns the number of cached entries
+func (c *FingerprintCache) Len() int {
+c.mu.RLock()
+defer c.mu.RUnlock()
+return len(c.entries)
+}
+
+// Save writes the cache back to the file it was opened from
+// Entries stored on disk by other processes are merged in, with entries in
+// memory taking precedence and entries removed by Invalidate or Prune left
+// out; the file is replaced atomically while holding a lock file, so
+// processes sharing the cache do not lose each other's entries
+func (c *FingerprintCache) Save() error {
+if c.path == "" {
+return fmt.Errorf("fingerprint cache has no file path")
+}
+
+c.mu.Lock()
+defer c.mu.Unlock()
+
+if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+return err
+}
+unlock, err := lockFingerprintCacheFile(c.path)
+if err != nil {
+return err
+}
+defer unlock()
+
+onDisk, err := readFingerprintCacheFile(c.path)
+if err != nil {
+return err
+}
+for key, entry := range onDisk {
+if _, ok := c.entries[key]; !ok && !c.deleted[key] {
+c.entries[key] = entry
+}
+}
+
+data, err := json.Marshal(fingerprintCacheFile{Version: fingerprintCacheVersion, Entries: c.entries})
+if err != nil {
+return fmt.Errorf("failed to marshal fingerprint cache: %w", err)
+}
+
+tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+if err != nil {
+return err
+}
+if _, err := tmp.Write(data); err != nil {
+tmp.Close()
+os.Remove(tmp.Name())
+return err
+}
+if err := tmp.Close(); err != nil {
+os.Remove(tmp.Name())
+return err
+}
+if err := os.Rename(tmp.Name(), c.path); err != nil {
+os.Remove(tmp.Name())
+return fmt.Errorf("failed to replace fingerprint cache: %w", err)
+}
+c.deleted = make(map[string]bool)
+return nil
+}
+
+// lockFingerprintCacheFile takes the lock file next to the cache file and
+// returns the function releasing it
+// A lock older than fingerprintCacheLockStale is assumed to be left behind by
+// a crashed process and taken over
+func lockFingerprintCacheFile(path string) (func(), error) {
+lockPath := path + ".lock"
+deadline := time.Now().Add(fingerprintCacheLockTimeout)
+for {
+lock, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+if err == nil {
+lock.Close()
+return func() { os.Remove(lockPath) }, nil
+}
+if !errors.Is(err, os.ErrExist) {
+return nil, fmt.Errorf("failed to lock fingerprint cache: %w", err)
+}
+if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fingerprintCacheLockStale {
+os.Remove(lockPath)
+continue
+}
+if time.Now().After(deadline) {
+return nil, fmt.Errorf("failed to lock fingerprint cache: %s is held by another process", lockPath)
+}
+time.Sleep(fingerprintCacheLockRetry)
+}
+}
@@ -0,0 +1,167 @@
+package curseforge
+
+import (
+"os"
+"path/filepath"
+"sync"
+"testing"
+"time"
+)
+
+func TestFingerprintCache(t *testing.T) {
+dir := t.TempDir()
+path := filepath.Join(dir, "mod.jar")
+data := fingerprintTestData(2048)
+if err := os.WriteFile(path, data, 0o644); err != nil {
+t.Fatal(err)
+}
+
+cache := NewFingerprintCache()
+if _, ok := cache.Lookup(path); ok {
+t.Fatal("empty cache returned an entry")
+}
+
+fp, err := cache.Fingerprint(path)
+if err != nil {
+t.Fatalf("Fingerprint failed: %v", err)
+}
+if fp != ComputeFingerprint(data) {
+t.Errorf("Fingerprint = %d, want %d", fp, ComputeFingerprint(data))
+}
+if _, ok := cache.Lookup(path); !ok {
+t.Fatal("fingerprint was not cached")
+}
+
+// Changing the file invalidates the entry
+changed := fingerprintTestData(4096)
+if err := os.WriteFile(path, changed, 0o644); err != nil {
+t.Fatal(err)
+}
+later := time.Now().Add(time.Minute)
+os.Chtimes(path, later, later)
+
+if _, ok := cache.Lookup(path); ok {
+t.Fatal("stale entry returned after the file changed")
+}
+fp, err = cache.Fingerprint(path)
+if err != nil {
+t.Fatalf("Fingerprint failed: %v", err)
+}
+if fp != ComputeFingerprint(changed) {
+t.Errorf("Fingerprint after change = %d, want %d", fp, ComputeFingerprint(changed))
+}
+
+os.Remove(path)
+if removed := cache.Prune(); removed != 1 || cache.Len() != 0 {
+t.Errorf("Prune removed %d entries, %d left", removed, cache.Len())
+}
+}
+
+func TestFingerprintCachePersistence(t *testing.T) {
+dir := t.TempDir()
+cachePath := filepath.Join(dir, "cache", "fingerprints.json")
+
+var paths []string
+for i := 0; i < 20; i++ {
+path := filepath.Join(dir, "mod"+string(rune('a'+i))+".jar")
+if err := os.WriteFile(path, fingerprintTestData(100+i), 0o644); err != nil {
+t.Fatal(err)
+}
+paths = append(paths, path)
+}
+
+first, err := OpenFingerprintCache(cachePath)
+if err != nil {
+t.Fatalf("OpenFingerprintCache failed: %v", err)
+}
+second, err := OpenFingerprintCache(cachePath)
+if err != nil {
+t.Fatalf("OpenFingerprintCache failed: %v", err)
+}
+
+// Two caches sharing a file, each used by several goroutines
+var wg sync.WaitGroup
+for i, path := range paths {
+cache := first
+if i%2 == 1 {
+cache = second
+}
+wg.Add(1)
+go func(cache *FingerprintCache, path string) {
+defer wg.Done()
+if _, err := cache.Fingerprint(path); err != nil {
+t.Errorf("Fingerprint failed: %v", err)
+}
+}(cache, path)
+}
+wg.Wait()
+
+if err := first.Save(); err != nil {
+t.Fatalf("Save failed: %v", err)
+}
+if err := second.Save(); err != nil {
+t.Fatalf("Save failed: %v", err)
+}
+
+reopened, err := OpenFingerprintCache(cachePath)
+if err != nil {
+t.Fatalf("OpenFingerprintCache failed: %v", err)
+}
+if reopened.Len() != len(paths) {
+t.Errorf("reopened cache has %d entries, want %d", reopened.Len(), len(paths))
+}
+for _, path := range paths {
+if _, ok := reopened.Lookup(path); !ok {
+t.Errorf("%s missing from reopened cache", path)
+}
+}
+}
+
+func TestFingerprintCacheSaveDropsRemovedEntries(t *testing.T) {
+dir := t.TempDir()
+cachePath := filepath.Join(dir, "fingerprints.json")
+kept := filepath.Join(dir, "kept.jar")
+deleted := filepath.Join(dir, "deleted.jar")
+invalidated := filepath.Join(dir, "invalidated.jar")
+for i, path := range []string{kept, deleted, invalidated} {
+if err := os.WriteFile(path, fingerprintTestData(100+i), 0o644); err != nil {
+t.Fatal(err)
+}
+}
+
+cache, err := OpenFingerprintCache(cachePath)
+if err != nil {
+t.Fatalf("OpenFingerprintCache failed: %v", err)
+}
+for _, path := range []string{kept, deleted, invalidated} {
+if _, err := cache.Fingerprint(path); err != nil {
+t.Fatalf("Fingerprint failed: %v", err)
+}
+}
+if err := cache.Save(); err != nil {
+t.Fatalf("Save failed: %v", err)
+}
+
+os.Remove(deleted)
+if removed := cache.Prune(); removed != 1 {
+t.Errorf("Prune removed %d entries, want 1", removed)
+}
+cache.Invalidate(invalidated)
+if err := cache.Save(); err != nil {
+t.Fatalf("Save failed: %v", err)
+}
+
+reopened, err := OpenFingerprintCache(cachePath)
+if err != nil {
+t.Fatalf("OpenFingerprintCache failed: %v", err)
+}
+if reopened.Len() != 1 {
+t.Errorf("reopened cache has %d entries, want only the kept file", reopened.Len())
+}
+if _, ok := reopened.Lookup(kept); !ok {
+t.Error("kept file missing from reopened cache")
+}
+if _, err := os.Stat(cachePath + ".lock"); !os.IsNotExist(err) {
+t.Errorf("lock file left behind: %v", err)
+}
+}
@@ -0,0 +1,394 @@
+package curseforge
+
+import (
+"errors"
+"fmt"
+"regexp"
+"strconv"
+"strings"
+"time"
+)
+
+// GameVersionKind is the kind of a Minecraft version
+// Kinds of the same release are ordered snapshot < pre-release < release candidate < release
+type GameVersionKind int
+
+const (
+GameVersionSnapshot         GameVersionKind = 1 // e.g. "1.20-Snapshot" as listed by CurseForge
+GameVersionPreRelease       GameVersionKind = 2 // e.g. "1.20-pre1"
+GameVersionReleaseCandidate GameVersionKind = 3 // e.g. "1.20-rc1"
+GameVersionRelease          GameVersionKind = 4 // e.g. "1.20.1"
+GameVersionWeeklySnapshot   GameVersionKind = 5 // e.g. "23w14a"
+)
+
+// String returns the string representation of GameVersionKind
+func (k GameVersionKind) String() string {
+switch k {
+case GameVersionSnapshot:
+return "snapshot"
+case GameVersionPreRelease:
+return "pre-release"
+case GameVersionReleaseCandidate:
+return "release candidate"
+case GameVersionRelease:
+return "release"
+case GameVersionWeeklySnapshot:
+return "weekly snapshot"
+default:
+return "unknown"
+}
+}
+
+// ParsedGameVersion is a parsed Minecraft version
+type ParsedGameVersion struct {
+Raw     string
+Kind    GameVersionKind
+Release []int // release numbers, e.g. [1 20 1]; empty for weekly snapshots
+Build   int   // pre-release or release candidate number
+
+// Weekly snapshot parts, e.g. 23w14a is year 23, week 14, letter 'a'
+SnapshotYear   int
+SnapshotWeek   int
+SnapshotLetter byte
+
+ReleaseDate time.Time // only known for versions from SortableGameVersion
+}
+
+var (
+gameVersionPattern      = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)+)(?:(?:-|\s+)(pre|rc|pre-release|release candidate)[-\s]?(\d+)|-snapshot)?$`)
+weeklySnapshotPattern   = regexp.MustCompile(`(?i)^(\d{2})w(\d{2})([a-z])$`)
+errNotAMinecraftVersion = errors.New("not a Minecraft version")
+)
+
+// ParseGameVersion parses a Minecraft version such as "1.20.1", "1.20-pre1",
+// "1.20 Pre-Release 1", "1.20-rc1", "1.20-Snapshot" or "23w14a"
+func ParseGameVersion(version string) (ParsedGameVersion, error) {
+raw := strings.TrimSpace(version)
+
+if match := weeklySnapshotPattern.FindStringSubmatch(raw); match != nil {
+year, _ := strconv.Atoi(match[1])
+week, _ := strconv.Atoi(match[2])
+return ParsedGameVersion{
+Raw:            raw,
+Kind:           GameVersionWeeklySnapshot,
+SnapshotYear:   year,
+SnapshotWeek:   week,
+SnapshotLetter: strings.ToLower(match[3])[0],
+}, nil
+}
+
+match := gameVersionPattern.FindStringSubmatch(raw)
+if match == nil {
+return ParsedGameVersion{}, fmt.Errorf("failed to parse game version %q: %w", version, errNotAMinecraftVersion)
+}
+
+parsed := ParsedGameVersion{Raw: raw, Kind: GameVersionRelease}
+for _, part := range strings.Split(match[1], ".") {
+number, err := strconv.Atoi(part)
+if err != nil {
+return ParsedGameVersion{}, fmt.Errorf("failed to parse game version %q: %w", version, err)
+}
+parsed.Release = append(parsed.Release, number)
+}
+
+switch suffix := strings.ToLower(match[2]); {
+case suffix == "pre" || suffix == "pre-release":
+parsed.Kind = GameVersionPreRelease
+case suffix == "rc" || suffix == "release candidate":
+parsed.Kind = GameVersionReleaseCandidate
+case strings.HasSuffix(strings.ToLower(raw), "-snapshot"):
+parsed.Kind = GameVersionSnapshot
+}
+if match[3] != "" {
+parsed.Build, _ = strconv.Atoi(match[3])
+}
+return parsed, nil
+}
+
+// ParseSortableGameVersion parses a SortableGameVersion, keeping its release date
+// The padded form (e.g. "0000000001.0000000020.0000000001") is used when the
+// name is not a recognizable version
+func ParseSortableGameVersion(version SortableGameVersion) (ParsedGameVersion, error) {
+name := version.GameVersion
+if name == "" {
+name = version.GameVersionName
+}
+
+parsed, err := ParseGameVersion(name)
+if err != nil {
+parsed, err = parsePaddedGameVersion(version.GameVersionPadded)
+if err != nil {
+return ParsedGameVersion{}, fmt.Errorf("failed to parse game version %q: %w", name, errNotAMinecraftVersion)
+}
+parsed.Raw = name
+}
+parsed.ReleaseDate = version.GameVersionReleaseDate
+return parsed, nil
+}
+
+// parsePaddedGameVersion parses a zero-padded release such as "0000000001.0000000020"
+func parsePaddedGameVersion(padded string) (ParsedGameVersion, error) {
+parts := strings.Split(padded, ".")
+if len(parts) < 2 {
+return ParsedGameVersion{}, errNotAMinecraftVersion
+}
+
+parsed := ParsedGameVersion{Raw: padded, Kind: GameVersionRelease}
+for _, part := range parts {
+number, err := strconv.Atoi(part)
+if err != nil {
+return ParsedGameVersion{}, errNotAMinecraftVersion
+}
+parsed.Release = append(parsed.Release, number)
+}
+return parsed, nil
+}
+
+// String returns the version as it was parsed
+func (v ParsedGameVersion) String() string {
+return v.Raw
+}
+
+// Compare orders v against other, returning -1, 0 or +1
+//
+// ok is false when the two cannot be ordered: a weekly snapshot and a
+// versioned release can only be compared when both release dates are known.
+// Missing trailing release numbers count as zero, so 1.20 equals 1.20.0.
+func (v ParsedGameVersion) Compare(other ParsedGameVersion) (result int, ok bool) {
+vWeekly, otherWeekly := v.Kind == GameVersionWeeklySnapshot, other.Kind == GameVersionWeeklySnapshot
+switch {
+case vWeekly && otherWeekly:
+return compareInts(
+[]int{v.SnapshotYear, v.SnapshotWeek, int(v.SnapshotLetter)},
+[]int{other.SnapshotYear, other.SnapshotWeek, int(other.SnapshotLetter)},
+), true
+case vWeekly || otherWeekly:
+if v.ReleaseDate.IsZero() || other.ReleaseDate.IsZero() {
+return 0, false
+}
+switch {
+case v.ReleaseDate.Before(other.ReleaseDate):
+return -1, true
+case v.ReleaseDate.After(other.ReleaseDate):
+return 1, true
+default:
+return 0, true
+}
+}
+
+if result := compareInts(v.Release, other.Release); result != 0 {
+return result, true
+}
+return compareInts([]int{int(v.Kind), v.Build}, []int{int(other.Kind), other.Build}), true
+}
+
+// compareInts compares two number sequences, padding the shorter with zeros
+func compareInts(a, b []int) int {
+for i := 0; i < len(a) || i < len(b); i++ {
+var x, y int
+if i < len(a) {
+x = a[i]
+}
+if i < len(b) {
+y = b[i]
+}
+switch {
+case x < y:
+return -1
+case x > y:
+return 1
+}
+}
+return 0
+}
+
+// ParsedGameVersions returns the Minecraft versions a file supports as
+// ParsedGameVersion values, see File.MinecraftVersions
+func (f File) ParsedGameVersions() []ParsedGameVersion {
+return f.MinecraftVersions()
+}
+
+// constraintOperator is a comparison in a version constraint term
+type constraintOperator string
+
+const (
+operatorEqual          constraintOperator = "="
+operatorNotEqual       constraintOperator = "!="
+operatorGreater        constraintOperator = ">"
+operatorGreaterOrEqual constraintOperator = ">="
+operatorLess           constraintOperator = "<"
+operatorLessOrEqual    constraintOperator = "<="
+)
+
+// constraintTerm is a single comparison, e.g. ">=1.19.2" or "1.20.x"
+type constraintTerm struct {
+operator constraintOperator
+version  ParsedGameVersion
+prefix   []int // set for wildcards such as "1.20.x"
+any      bool  // "*"
+}
+
+// GameVersionConstraint is a set of accepted game versions
+//
+// Terms separated by whitespace must all match and alternatives are separated
+// by "||", e.g. ">=1.19.2 <1.21 || 1.21.1". A term is a version with an optional
+// operator (=, !=, >, >=, <, <=), a wildcard such as "1.20.x" or "1.20.*", or "*"
+// for any version. Ranges include the snapshots and pre-releases falling inside
+// them, so "<1.21" accepts "1.21-pre1".
+type GameVersionConstraint struct {
+raw          string
+alternatives [][]constraintTerm
+}
+
+// ParseGameVersionConstraint parses a version constraint, see GameVersionConstraint
+func ParseGameVersionConstraint(constraint string) (*GameVersionConstraint, error) {
+parsed := &GameVersionConstraint{raw: strings.TrimSpace(constraint)}
+for _, alternative := range strings.Split(constraint, "||") {
+fields := strings.Fields(alternative)
+if len(fields) == 0 {
+return nil, fmt.Errorf("failed to parse version constraint %q: empty alternative", constraint)
+}
+
+var terms []constraintTerm
+for i := 0; i < len(fields); i++ {
+field := fields[i]
+// Allow a space between the operator and the version, e.g. ">= 1.19"
+if strings.Trim(field, "<>=!") == "" && i+1 < len(fields) {
+i++
+field += fields[i]
+}
+term, err := parseConstraintTerm(field)
+if err != nil {
+return nil, fmt.Errorf("failed to parse version constraint %q: %w", constraint, err)
+}
+terms = append(terms, term)
+}
+parsed.alternatives = append(parsed.alternatives, terms)
+}
+return parsed, nil
+}
+
+// MustParseGameVersionConstraint is like ParseGameVersionConstraint but panics on error
+func MustParseGameVersionConstraint(constraint string) *GameVersionConstraint {
+parsed, err := ParseGameVersionConstraint(constraint)
+if err != nil {
+panic(err)
+}
+return parsed
+}
+
+// parseConstraintTerm parses one term of a constraint
+func parseConstraintTerm(field string) (constraintTerm, error) {
+if field == "*" || strings.EqualFold(field, "x") {
+return constraintTerm{any: true}, nil
+}
+
+term := constraintTerm{operator: operatorEqual}
+for _, operator := range []constraintOperator{operatorGreaterOrEqual, operatorLessOrEqual, operatorNotEqual, operatorGreater, operatorLess, operatorEqual} {
+if rest, found := strings.CutPrefix(field, string(operator)); found {
+term.operator = operator
+field = rest
+break
+}
+}
+
+if strings.HasSuffix(field, ".x") || strings.HasSuffix(field, ".X") || strings.HasSuffix(field, ".*") {
+if term.operator != operatorEqual {
+return constraintTerm{}, fmt.Errorf("wildcard %q cannot be combined with %s", field, term.operator)
+}
+prefix, err := ParseGameVersion(field[:len(field)-2] + ".0")
+if err != nil || prefix.Kind != GameVersionRelease {
+return constraintTerm{}, fmt.Errorf("invalid wildcard %q", field)
+}
+term.prefix = prefix.Release[:len(prefix.Release)-1]
+return term, nil
+}
+
+version, err := ParseGameVersion(field)
+if err != nil {
+return constraintTerm{}, err
+}
+term.version = version
+return term, nil
+}
+
+// String returns the constraint as it was parsed
+func (c *GameVersionConstraint) String() string {
+return c.raw
+}
+
+// Matches reports whether a version satisfies the constraint
+func (c *GameVersionConstraint) Matches(version ParsedGameVersion) bool {
+for _, terms := range c.alternatives {
+matched := true
+for _, term := range terms {
+if !term.matches(version) {
+matched = false
+break
+}
+}
+if matched {
+return true
+}
+}
+return false
+}
+
+// MatchesString reports whether a version string satisfies the constraint
+// Strings that are not Minecraft versions never match
+func (c *GameVersionConstraint) MatchesString(version string) bool {
+parsed, err := ParseGameVersion(version)
+return err == nil && c.Matches(parsed)
+}
+
+// MatchingVersions returns the versions a file supports that satisfy the constraint
+func (c *GameVersionConstraint) MatchingVersions(file File) []ParsedGameVersion {
+var matching []ParsedGameVersion
+for _, version := range file.MinecraftVersions() {
+if c.Matches(version) {
+matching = append(matching, version)
+}
+}
+return matching
+}
+
+// MatchesFile reports whether a file supports any version satisfying the constraint
+func (c *GameVersionConstraint) MatchesFile(file File) bool {
+return len(c.MatchingVersions(file)) > 0
+}
+
+// matches checks a version against a single term
+func (t constraintTerm) matches(version ParsedGameVersion) bool {
+if t.any {
+return true
+}
+if t.prefix != nil {
+if version.Kind == GameVersionWeeklySnapshot || len(version.Release) < len(t.prefix) {
+return false
+}
+return compareInts(version.Release[:len(t.prefix)], t.prefix) == 0
+}
+
+if t.operator == operatorEqual && strings.EqualFold(version.Raw, t.version.Raw) {
+return true
+}
+result, ok := version.Compare(t.version)
+if !ok {
+return t.operator == operatorNotEqual
+}
+switch t.operator {
+case operatorEqual:
+return result == 0
+case operatorNotEqual:
+return result != 0
+case operatorGreater:
+return result > 0
+case operatorGreaterOrEqual:
+return result >= 0
+case operatorLess:
+return result < 0
+case operatorLessOrEqual:
+return result <= 0
+}
+return false
+}
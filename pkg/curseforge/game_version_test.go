@@ -0,0 +1,193 @@
+package curseforge
+
+import (
+"testing"
+"time"
+)
+
+func TestParseGameVersion(t *testing.T) {
+tests := []struct {
+input   string
+kind    GameVersionKind
+release []int
+build   int
+wantErr bool
+}{
+{input: "1.20.1", kind: GameVersionRelease, release: []int{1, 20, 1}},
+{input: "1.20", kind: GameVersionRelease, release: []int{1, 20}},
+{input: "1.20-pre1", kind: GameVersionPreRelease, release: []int{1, 20}, build: 1},
+{input: "1.14 Pre-Release 5", kind: GameVersionPreRelease, release: []int{1, 14}, build: 5},
+{input: "1.20.2-rc2", kind: GameVersionReleaseCandidate, release: []int{1, 20, 2}, build: 2},
+{input: "1.16 Release Candidate 1", kind: GameVersionReleaseCandidate, release: []int{1, 16}, build: 1},
+{input: "1.20-Snapshot", kind: GameVersionSnapshot, release: []int{1, 20}},
+{input: "23w14a", kind: GameVersionWeeklySnapshot},
+{input: "Forge", wantErr: true},
+{input: "Java 17", wantErr: true},
+{input: "1", wantErr: true},
+}
+
+for _, tt := range tests {
+t.Run(tt.input, func(t *testing.T) {
+version, err := ParseGameVersion(tt.input)
+if (err != nil) != tt.wantErr {
+t.Fatalf("ParseGameVersion() error = %v, wantErr %v", err, tt.wantErr)
+}
+if tt.wantErr {
+return
+}
+if version.Kind != tt.kind || compareInts(version.Release, tt.release) != 0 || len(version.Release) != len(tt.release) || version.Build != tt.build {
+t.Errorf("ParseGameVersion() = %+v", version)
+}
+})
+}
+
+snapshot, _ := ParseGameVersion("23w14a")
+if snapshot.SnapshotYear != 23 || snapshot.SnapshotWeek != 14 || snapshot.SnapshotLetter != 'a' {
+t.Errorf("weekly snapshot parts = %+v", snapshot)
+}
+}
+
+func TestParsedGameVersionCompare(t *testing.T) {
+tests := []struct {
+a, b     string
+expected int
+ok       bool
+}{
+{a: "1.20.1", b: "1.20", expected: 1, ok: true},
+{a: "1.20", b: "1.20.0", expected: 0, ok: true},
+{a: "1.9", b: "1.10", expected: -1, ok: true},
+{a: "1.20-Snapshot", b: "1.20-pre1", expected: -1, ok: true},
+{a: "1.20-pre2", b: "1.20-pre1", expected: 1, ok: true},
+{a: "1.20-pre7", b: "1.20-rc1", expected: -1, ok: true},
+{a: "1.20-rc1", b: "1.20", expected: -1, ok: true},
+{a: "1.20-rc1", b: "1.19.4", expected: 1, ok: true},
+{a: "23w14a", b: "23w13b", expected: 1, ok: true},
+{a: "23w14a", b: "23w14b", expected: -1, ok: true},
+{a: "23w14a", b: "1.19.4", ok: false},
+}
+
+for _, tt := range tests {
+t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+a, _ := ParseGameVersion(tt.a)
+b, _ := ParseGameVersion(tt.b)
+result, ok := a.Compare(b)
+if result != tt.expected || ok != tt.ok {
+t.Errorf("Compare() = %d, %v, want %d, %v", result, ok, tt.expected, tt.ok)
+}
+})
+}
+}
+
+func TestParseSortableGameVersion(t *testing.T) {
+released := time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC)
+release, err := ParseSortableGameVersion(SortableGameVersion{
+GameVersionName:        "1.19.4",
+GameVersionPadded:      "0000000001.0000000019.0000000004",
+GameVersion:            "1.19.4",
+GameVersionReleaseDate: released,
+})
+if err != nil {
+t.Fatalf("ParseSortableGameVersion failed: %v", err)
+}
+
+snapshot, _ := ParseSortableGameVersion(SortableGameVersion{GameVersionName: "23w14a", GameVersionReleaseDate: released.AddDate(0, 0, 21)})
+if result, ok := snapshot.Compare(release); !ok || result != 1 {
+t.Errorf("snapshot with release date Compare() = %d, %v, want 1, true", result, ok)
+}
+
+padded, err := ParseSortableGameVersion(SortableGameVersion{GameVersionName: "Minecraft 1.20", GameVersionPadded: "0000000001.0000000020"})
+if err != nil || compareInts(padded.Release, []int{1, 20}) != 0 || padded.Raw != "Minecraft 1.20" {
+t.Errorf("padded fallback = %+v, %v", padded, err)
+}
+
+if _, err := ParseSortableGameVersion(SortableGameVersion{GameVersionName: "Forge", GameVersionPadded: "0"}); err == nil {
+t.Error("expected error for a loader entry")
+}
+}
+
+func TestGameVersionConstraint(t *testing.T) {
+tests := []struct {
+constraint string
+matches    []string
+rejects    []string
+}{
+{
+constraint: "1.20.x",
+matches:    []string{"1.20", "1.20.1", "1.20.4", "1.20-pre1"},
+rejects:    []string{"1.19.4", "1.21", "23w14a", "Forge"},
+},
+{
+constraint: ">=1.19.2 <1.21",
+matches:    []string{"1.19.2", "1.19.4", "1.20.6", "1.21-pre1"},
+rejects:    []string{"1.19.1", "1.21", "1.21.1", "23w14a"},
+},
+{
+constraint: "1.20.1 || 1.20.2",
+matches:    []string{"1.20.1", "1.20.2"},
+rejects:    []string{"1.20", "1.20.3"},
+},
+{
+constraint: ">= 1.18 != 1.19.3",
+matches:    []string{"1.18", "1.19.2"},
+rejects:    []string{"1.17.1", "1.19.3", "23w14a"},
+},
+{
+constraint: "23w14a || *",
+matches:    []string{"23w14a", "1.7.10"},
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.constraint, func(t *testing.T) {
+constraint, err := ParseGameVersionConstraint(tt.constraint)
+if err != nil {
+t.Fatalf("ParseGameVersionConstraint failed: %v", err)
+}
+for _, version := range tt.matches {
+if !constraint.MatchesString(version) {
+t.Errorf("%q should match %q", tt.constraint, version)
+}
+}
+for _, version := range tt.rejects {
+if constraint.MatchesString(version) {
+t.Errorf("%q should not match %q", tt.constraint, version)
+}
+}
+})
+}
+
+for _, invalid := range []string{"", "1.20 ||", ">=1.20.x", "latest"} {
+if _, err := ParseGameVersionConstraint(invalid); err == nil {
+t.Errorf("expected error for constraint %q", invalid)
+}
+}
+}
+
+func TestSelectFileFromVersionConstraint(t *testing.T) {
+candidates := []File{
+selectorTestFile(1, 5, ReleaseTypeRelease, "1.19.2", "Forge"),
+selectorTestFile(2, 4, ReleaseTypeRelease, "1.20", "1.20.1", "Forge"),
+selectorTestFile(3, 9, ReleaseTypeRelease, "1.21", "Forge"),
+}
+
+selection := SelectFileFrom(candidates, FileConstraints{
+VersionConstraint: MustParseGameVersionConstraint(">=1.19.2 <1.21"),
+ModLoader:         ModLoaderForge,
+})
+if !selection.Found() || selection.File.ID != 1 {
+t.Fatalf("selected %s, want newest matching file 1", selection.Explain())
+}
+
+selection = SelectFileFrom(candidates, FileConstraints{
+GameVersion:       "1.20.1",
+VersionConstraint: MustParseGameVersionConstraint("1.19.x"),
+})
+if !selection.Found() || selection.File.ID != 2 || selection.GameVersion != "1.20.1" {
+t.Errorf("exact GameVersion should win over the constraint: %s", selection.Explain())
+}
+
+selection = SelectFileFrom(candidates[1:2], FileConstraints{VersionConstraint: MustParseGameVersionConstraint("1.20.x")})
+if selection.GameVersion != "1.20.1" {
+t.Errorf("GameVersion = %q, want highest matching version 1.20.1", selection.GameVersion)
+}
+}
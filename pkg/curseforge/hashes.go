@@ -0,0 +1,157 @@
+package curseforge
+
+import (
+"crypto/md5"
+"crypto/sha1"
+"encoding/hex"
+"hash"
+"io"
+"os"
+)
+
+// FileHashes holds the hashes CurseForge publishes for a file
+// Sha1 and Md5 are lowercase hex, matching the values in File.Hashes
+type FileHashes struct {
+Size              int64
+Sha1              string
+Md5               string
+Fingerprint       int64
+FingerprintLength int64 // number of non-whitespace bytes, see CountFingerprintBytes
+HasFingerprint    bool  // false when the fingerprint length was not known while hashing
+}
+
+// Value returns the hash for the given algorithm, or "" if unknown
+func (h FileHashes) Value(algo HashAlgo) string {
+switch algo {
+case HashAlgoSha1:
+return h.Sha1
+case HashAlgoMd5:
+return h.Md5
+default:
+return ""
+}
+}
+
+// MultiHasher computes SHA1, MD5 and the CurseForge fingerprint from a single
+// stream of writes, e.g. as an io.MultiWriter target while a download is saved
+//
+// SHA1 and MD5 need no setup. The Murmur2 fingerprint mixes the filtered length
+// into its initial state, so it is only computed when that length is supplied
+// via NewMultiHasherWithFingerprintLength. Without it the hasher still counts the
+// filtered length, so one extra pass with NewMurmur2Hasher completes the fingerprint.
+type MultiHasher struct {
+sha1    hash.Hash
+md5     hash.Hash
+murmur  *Murmur2Hasher
+counter whitespaceCounter
+size    int64
+}
+
+// NewMultiHasher creates a hasher computing SHA1 and MD5 and counting the
+// fingerprint length of everything written to it
+func NewMultiHasher() *MultiHasher {
+return &MultiHasher{
+sha1: sha1.New(),
+md5:  md5.New(),
+}
+}
+
+// NewMultiHasherWithFingerprintLength creates a hasher that also computes the
+// fingerprint, given the filtered length from CountFingerprintBytes
+func NewMultiHasherWithFingerprintLength(filteredLength int64) *MultiHasher {
+m := NewMultiHasher()
+m.murmur = NewMurmur2Hasher(filteredLength)
+return m
+}
+
+// Write feeds data into every hash
+func (m *MultiHasher) Write(p []byte) (int, error) {
+if m.murmur != nil {
+if _, err := m.murmur.Write(p); err != nil {
+return 0, err
+}
+}
+m.sha1.Write(p)
+m.md5.Write(p)
+m.counter.Write(p)
+m.size += int64(len(p))
+return len(p), nil
+}
+
+// Hashes returns the hashes of everything written so far
+func (m *MultiHasher) Hashes() (*FileHashes, error) {
+hashes := &FileHashes{
+Size:              m.size,
+Sha1:              hex.EncodeToString(m.sha1.Sum(nil)),
+Md5:               hex.EncodeToString(m.md5.Sum(nil)),
+FingerprintLength: m.counter.count,
+}
+
+if m.murmur != nil {
+fingerprint, err := m.murmur.Fingerprint()
+if err != nil {
+return nil, err
+}
+hashes.Fingerprint = fingerprint
+hashes.HasFingerprint = true
+}
+return hashes, nil
+}
+
+// ComputeFileHashes computes the fingerprint, SHA1 and MD5 of a file
+// The file is hashed in a single pass after a cheap whitespace-counting
+// pre-pass, with constant memory regardless of its size
+func ComputeFileHashes(filePath string) (*FileHashes, error) {
+file, err := os.Open(filePath)
+if err != nil {
+return nil, err
+}
+defer file.Close()
+
+return ComputeHashesFromReadSeeker(file)
+}
+
+// ComputeHashesFromReadSeeker computes the fingerprint, SHA1 and MD5 starting
+// at the reader's current offset
+func ComputeHashesFromReadSeeker(reader io.ReadSeeker) (*FileHashes, error) {
+start, err := reader.Seek(0, io.SeekCurrent)
+if err != nil {
+return nil, err
+}
+
+length, err := CountFingerprintBytes(reader)
+if err != nil {
+return nil, err
+}
+
+if _, err := reader.Seek(start, io.SeekStart); err != nil {
+return nil, err
+}
+
+hasher := NewMultiHasherWithFingerprintLength(length)
+if _, err := io.Copy(hasher, reader); err != nil {
+return nil, err
+}
+return hasher.Hashes()
+}
+
+// CompleteFingerprint fills in the fingerprint of hashes computed without a
+// known fingerprint length by streaming the same content once more
+func (h *FileHashes) CompleteFingerprint(reader io.Reader) error {
+if h.HasFingerprint {
+return nil
+}
+
+hasher := NewMurmur2Hasher(h.FingerprintLength)
+if _, err := io.Copy(hasher, reader); err != nil {
+return err
+}
+
+fingerprint, err := hasher.Fingerprint()
+if err != nil {
+return err
+}
+h.Fingerprint = fingerprint
+h.HasFingerprint = true
+return nil
+}
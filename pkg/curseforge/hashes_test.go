@@ -0,0 +1,65 @@
+package curseforge
+
+import (
+"bytes"
+"crypto/md5"
+"crypto/sha1"
+"encoding/hex"
+"os"
+"path/filepath"
+"testing"
+)
+
+func TestComputeFileHashes(t *testing.T) {
+data := fingerprintTestData(50000)
+path := filepath.Join(t.TempDir(), "mod.jar")
+if err := os.WriteFile(path, data, 0o644); err != nil {
+t.Fatal(err)
+}
+
+hashes, err := ComputeFileHashes(path)
+if err != nil {
+t.Fatalf("ComputeFileHashes failed: %v", err)
+}
+
+sha1Sum := sha1.Sum(data)
+md5Sum := md5.Sum(data)
+if hashes.Sha1 != hex.EncodeToString(sha1Sum[:]) {
+t.Errorf("Sha1 = %s, want %x", hashes.Sha1, sha1Sum)
+}
+if hashes.Md5 != hex.EncodeToString(md5Sum[:]) {
+t.Errorf("Md5 = %s, want %x", hashes.Md5, md5Sum)
+}
+if !hashes.HasFingerprint || hashes.Fingerprint != ComputeFingerprint(data) {
+t.Errorf("Fingerprint = %d, want %d", hashes.Fingerprint, ComputeFingerprint(data))
+}
+if hashes.Size != int64(len(data)) {
+t.Errorf("Size = %d, want %d", hashes.Size, len(data))
+}
+if hashes.Value(HashAlgoSha1) != hashes.Sha1 || hashes.Value(HashAlgoMd5) != hashes.Md5 {
+t.Error("Value does not map hash algorithms")
+}
+}
+
+func TestMultiHasherCompleteFingerprint(t *testing.T) {
+data := fingerprintTestData(4099)
+
+hasher := NewMultiHasher()
+hasher.Write(data[:1000])
+hasher.Write(data[1000:])
+
+hashes, err := hasher.Hashes()
+if err != nil {
+t.Fatalf("Hashes failed: %v", err)
+}
+if hashes.HasFingerprint {
+t.Fatal("fingerprint should not be available without a known length")
+}
+
+if err := hashes.CompleteFingerprint(bytes.NewReader(data)); err != nil {
+t.Fatalf("CompleteFingerprint failed: %v", err)
+}
+if hashes.Fingerprint != ComputeFingerprint(data) {
+t.Errorf("Fingerprint = %d, want %d", hashes.Fingerprint, ComputeFingerprint(data))
+}
+}
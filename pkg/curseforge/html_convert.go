@@ -0,0 +1,499 @@
+package curseforge
+
+import (
+"bytes"
+"fmt"
+"net/url"
+"regexp"
+"strconv"
+"strings"
+
+"golang.org/x/net/html"
+"golang.org/x/net/html/atom"
+)
+
+// HTMLLink is a link found in a description or changelog
+type HTMLLink struct {
+URL  string // with CurseForge linkout redirects unwrapped
+Text string
+}
+
+// HTMLImage is an image found in a description or changelog
+type HTMLImage struct {
+URL   string
+Alt   string
+Title string
+}
+
+// HTMLToMarkdown converts CurseForge description or changelog HTML to Markdown
+// Headings, lists, links, images, tables, code and quotes are kept; linkout
+// redirect URLs are replaced by their targets and embedded videos become links
+func HTMLToMarkdown(content string) (string, error) {
+return convertHTML(content, true)
+}
+
+// HTMLToText converts CurseForge description or changelog HTML to plain text
+// for terminals and chat messages; link targets follow their text in parentheses
+func HTMLToText(content string) (string, error) {
+return convertHTML(content, false)
+}
+
+// convertHTML parses content and renders it in either output format
+func convertHTML(content string, markdown bool) (string, error) {
+doc, err := html.Parse(strings.NewReader(content))
+if err != nil {
+return "", fmt.Errorf("failed to parse HTML: %w", err)
+}
+
+r := &htmlRenderer{markdown: markdown}
+r.renderChildren(doc)
+return normalizeRendered(r.b.String()), nil
+}
+
+// ExtractLinks returns the links of an HTML document in order of appearance
+// Links to the same URL are returned once; embedded frames are included without text
+func ExtractLinks(content string) ([]HTMLLink, error) {
+doc, err := html.Parse(strings.NewReader(content))
+if err != nil {
+return nil, fmt.Errorf("failed to parse HTML: %w", err)
+}
+
+var links []HTMLLink
+seen := make(map[string]bool)
+walkHTML(doc, func(n *html.Node) {
+var link HTMLLink
+switch n.DataAtom {
+case atom.A:
+link = HTMLLink{URL: htmlAttr(n, "href"), Text: collapseWhitespace(htmlText(n))}
+case atom.Iframe:
+link = HTMLLink{URL: htmlAttr(n, "src")}
+default:
+return
+}
+link.URL = UnwrapLinkoutURL(link.URL)
+if link.URL == "" || seen[link.URL] {
+return
+}
+seen[link.URL] = true
+links = append(links, link)
+})
+return links, nil
+}
+
+// ExtractImages returns the images of an HTML document in order of appearance
+// Images with the same URL are returned once
+func ExtractImages(content string) ([]HTMLImage, error) {
+doc, err := html.Parse(strings.NewReader(content))
+if err != nil {
+return nil, fmt.Errorf("failed to parse HTML: %w", err)
+}
+
+var images []HTMLImage
+seen := make(map[string]bool)
+walkHTML(doc, func(n *html.Node) {
+if n.DataAtom != atom.Img {
+return
+}
+image := HTMLImage{URL: htmlAttr(n, "src"), Alt: htmlAttr(n, "alt"), Title: htmlAttr(n, "title")}
+if image.URL == "" || seen[image.URL] {
+return
+}
+seen[image.URL] = true
+images = append(images, image)
+})
+return images, nil
+}
+
+// UnwrapLinkoutURL returns the target of a CurseForge linkout redirect such as
+// https://www.curseforge.com/linkout?remoteUrl=https%253a%252f%252fgithub.com
+// The target is usually URL-encoded twice; it is decoded a second time only
+// while it is not yet an absolute URL. Other URLs are returned unchanged
+func UnwrapLinkoutURL(link string) string {
+parsed, err := url.Parse(strings.TrimSpace(link))
+if err != nil || !strings.HasSuffix(parsed.Path, "/linkout") {
+return link
+}
+remote := parsed.Query().Get("remoteUrl")
+if remote == "" {
+return link
+}
+// Query().Get has decoded once already; decoding an absolute URL again
+// would corrupt escapes such as %26 in its own query
+if !isAbsoluteURL(remote) {
+if decoded, err := url.QueryUnescape(remote); err == nil {
+remote = decoded
+}
+}
+return remote
+}
+
+// isAbsoluteURL reports whether link parses as a URL with a scheme and host
+func isAbsoluteURL(link string) bool {
+parsed, err := url.Parse(link)
+return err == nil && parsed.IsAbs() && parsed.Host != ""
+}
+
+// htmlRenderer renders a parsed HTML tree as Markdown or plain text
+type htmlRenderer struct {
+markdown bool
+b        bytes.Buffer
+lists    []htmlList
+}
+
+// htmlList is an open ul or ol element
+type htmlList struct {
+ordered bool
+index   int
+}
+
+// renderChildren renders every child of n
+func (r *htmlRenderer) renderChildren(n *html.Node) {
+for child := n.FirstChild; child != nil; child = child.NextSibling {
+r.render(child)
+}
+}
+
+// render renders a single node
+func (r *htmlRenderer) render(n *html.Node) {
+switch n.Type {
+case html.TextNode:
+r.writeText(n.Data)
+return
+case html.DocumentNode:
+r.renderChildren(n)
+return
+case html.ElementNode:
+default:
+return
+}
+
+switch n.DataAtom {
+case atom.Head, atom.Script, atom.Style, atom.Noscript:
+case atom.Br:
+if r.markdown {
+r.b.WriteString("  ")
+}
+r.b.WriteString("\n")
+case atom.Hr:
+r.block()
+r.b.WriteString("---")
+r.block()
+case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+r.block()
+text := r.inline(n)
+if text != "" {
+if r.markdown {
+level := int(n.Data[1] - '0')
+r.b.WriteString(strings.Repeat("#", level) + " ")
+}
+r.b.WriteString(text)
+}
+r.block()
+case atom.P, atom.Div, atom.Section, atom.Article, atom.Figure, atom.Center, atom.Header, atom.Footer:
+r.block()
+r.renderChildren(n)
+r.block()
+case atom.Ul, atom.Ol:
+r.block()
+r.lists = append(r.lists, htmlList{ordered: n.DataAtom == atom.Ol})
+r.renderChildren(n)
+r.lists = r.lists[:len(r.lists)-1]
+r.block()
+case atom.Li:
+r.renderListItem(n)
+case atom.Blockquote:
+r.block()
+quoted := r.sub(n)
+prefix := "> "
+if !r.markdown {
+prefix = "  "
+}
+for i, line := range strings.Split(quoted, "\n") {
+if i > 0 {
+r.b.WriteString("\n")
+}
+r.b.WriteString(strings.TrimRight(prefix+line, " "))
+}
+r.block()
+case atom.Pre:
+r.block()
+code := strings.Trim(htmlText(n), "\n")
+if r.markdown {
+r.b.WriteString("```\n" + code + "\n```")
+} else {
+r.b.WriteString(code)
+}
+r.block()
+case atom.Code, atom.Kbd, atom.Samp:
+if !r.markdown {
+r.renderChildren(n)
+return
+}
+r.wrapInline(n, "`")
+case atom.Strong, atom.B:
+r.wrapInline(n, "**")
+case atom.Em, atom.I:
+r.wrapInline(n, "_")
+case atom.S, atom.Del, atom.Strike:
+r.wrapInline(n, "~~")
+case atom.A:
+r.renderLink(n)
+case atom.Img:
+r.renderImage(n)
+case atom.Iframe:
+if src := htmlAttr(n, "src"); src != "" {
+r.block()
+if r.markdown {
+fmt.Fprintf(&r.b, "[Embedded video](%s)", src)
+} else {
+r.b.WriteString(src)
+}
+r.block()
+}
+case atom.Table:
+r.renderTable(n)
+default:
+r.renderChildren(n)
+}
+}
+
+// writeText writes a text node, collapsing whitespace
+func (r *htmlRenderer) writeText(text string) {
+text = collapseWhitespace(text)
+if r.atLineStart() {
+text = strings.TrimLeft(text, " ")
+}
+if text == "" {
+return
+}
+if r.markdown {
+text = escapeMarkdown(text)
+}
+r.b.WriteString(text)
+}
+
+// atLineStart reports whether the output is empty or ends with a newline,
+// ignoring trailing spaces
+func (r *htmlRenderer) atLineStart() bool {
+s := bytes.TrimRight(r.b.Bytes(), " ")
+return len(s) == 0 || s[len(s)-1] == '\n'
+}
+
+// block ends the current block; inside a list item only a line break is added
+func (r *htmlRenderer) block() {
+s := bytes.TrimRight(r.b.Bytes(), " \t")
+r.b.Truncate(len(s))
+if len(s) == 0 {
+return
+}
+if len(r.lists) > 0 {
+if !bytes.HasSuffix(s, []byte("\n")) {
+r.b.WriteString("\n")
+}
+return
+}
+switch {
+case bytes.HasSuffix(s, []byte("\n\n")):
+case bytes.HasSuffix(s, []byte("\n")):
+r.b.WriteString("\n")
+default:
+r.b.WriteString("\n\n")
+}
+}
+
+// sub renders the children of n with a fresh renderer in the same mode
+func (r *htmlRenderer) sub(n *html.Node) string {
+sub := &htmlRenderer{markdown: r.markdown}
+sub.renderChildren(n)
+return normalizeRendered(sub.b.String())
+}
+
+// inline renders the children of n on a single line
+func (r *htmlRenderer) inline(n *html.Node) string {
+return strings.Join(strings.Fields(strings.ReplaceAll(r.sub(n), "  \n", " ")), " ")
+}
+
+// wrapInline renders n's children between Markdown markers
+func (r *htmlRenderer) wrapInline(n *html.Node, marker string) {
+text := r.inline(n)
+if text == "" {
+return
+}
+if r.markdown {
+text = marker + text + marker
+}
+r.writeInline(text)
+}
+
+// writeInline writes already rendered inline content
+func (r *htmlRenderer) writeInline(text string) {
+r.b.WriteString(text)
+}
+
+// renderListItem renders an li element with its bullet or number
+func (r *htmlRenderer) renderListItem(n *html.Node) {
+if len(r.lists) == 0 {
+r.block()
+r.renderChildren(n)
+r.block()
+return
+}
+
+list := &r.lists[len(r.lists)-1]
+list.index++
+bullet := "- "
+if list.ordered {
+bullet = strconv.Itoa(list.index) + ". "
+}
+
+if !r.atLineStart() {
+r.b.WriteString("\n")
+}
+r.b.WriteString(strings.Repeat("  ", len(r.lists)-1) + bullet)
+r.renderChildren(n)
+r.block()
+}
+
+// renderLink renders an a element
+func (r *htmlRenderer) renderLink(n *html.Node) {
+href := UnwrapLinkoutURL(htmlAttr(n, "href"))
+text := r.inline(n)
+if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+r.writeInline(text)
+return
+}
+
+switch {
+case !r.markdown && (text == "" || text == href):
+r.writeInline(href)
+case !r.markdown:
+r.writeInline(text + " (" + href + ")")
+case text == "" || text == escapeMarkdown(href):
+r.writeInline("<" + href + ">")
+default:
+r.writeInline("[" + text + "](" + markdownURL(href) + ")")
+}
+}
+
+// renderImage renders an img element
+func (r *htmlRenderer) renderImage(n *html.Node) {
+src := htmlAttr(n, "src")
+alt := collapseWhitespace(htmlAttr(n, "alt"))
+if !r.markdown {
+if alt != "" {
+r.writeInline("[" + alt + "]")
+}
+return
+}
+if src == "" {
+return
+}
+r.writeInline("![" + escapeMarkdown(alt) + "](" + markdownURL(src) + ")")
+}
+
+// renderTable renders a table as a Markdown pipe table, or aligned rows of text
+func (r *htmlRenderer) renderTable(n *html.Node) {
+var rows [][]string
+walkHTML(n, func(row *html.Node) {
+if row.DataAtom != atom.Tr {
+return
+}
+var cells []string
+for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+cells = append(cells, strings.ReplaceAll(r.inline(cell), "|", `\|`))
+}
+}
+if len(cells) > 0 {
+rows = append(rows, cells)
+}
+})
+if len(rows) == 0 {
+return
+}
+
+columns := 0
+for _, row := range rows {
+if len(row) > columns {
+columns = len(row)
+}
+}
+
+r.block()
+for i, row := range rows {
+for len(row) < columns {
+row = append(row, "")
+}
+if r.markdown {
+r.b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+if i == 0 {
+r.b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+}
+} else {
+r.b.WriteString(strings.TrimRight(strings.Join(row, " | "), " ") + "\n")
+}
+}
+r.block()
+}
+
+var (
+whitespacePattern    = regexp.MustCompile(`[ \t\r\n\f\x{00a0}]+`)
+blankLinesPattern    = regexp.MustCompile(`\n{3,}`)
+markdownEscapeTarget = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+)
+
+// collapseWhitespace replaces runs of whitespace, including non-breaking spaces, by one space
+func collapseWhitespace(text string) string {
+return whitespacePattern.ReplaceAllString(text, " ")
+}
+
+// escapeMarkdown escapes characters that would otherwise start Markdown formatting
+func escapeMarkdown(text string) string {
+return markdownEscapeTarget.Replace(text)
+}
+
+// markdownURL makes a URL safe inside Markdown link parentheses
+func markdownURL(link string) string {
+return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(link)
+}
+
+// normalizeRendered trims whitespace-only lines and collapses blank lines
+func normalizeRendered(text string) string {
+lines := strings.Split(text, "\n")
+for i, line := range lines {
+if strings.TrimSpace(line) == "" {
+lines[i] = ""
+}
+}
+text = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+return strings.Trim(text, "\n")
+}
+
+// walkHTML calls fn for n and every descendant, depth first
+func walkHTML(n *html.Node, fn func(*html.Node)) {
+fn(n)
+for child := n.FirstChild; child != nil; child = child.NextSibling {
+walkHTML(child, fn)
+}
+}
+
+// htmlAttr returns an attribute of n, or ""
+func htmlAttr(n *html.Node, name string) string {
+for _, attr := range n.Attr {
+if attr.Key == name {
+return strings.TrimSpace(attr.Val)
+}
+}
+return ""
+}
+
+// htmlText returns the concatenated text content of n
+func htmlText(n *html.Node) string {
+var b strings.Builder
+walkHTML(n, func(node *html.Node) {
+if node.Type == html.TextNode {
+b.WriteString(node.Data)
+}
+})
+return b.String()
+}
@@ -0,0 +1,132 @@
+package curseforge
+
+import (
+"testing"
+)
+
+const testDescriptionHTML = `<h2>About   <strong>JEI</strong></h2>
+<p>JEI is an <em>item</em> and recipe viewing mod.<br>It is built from the ground up for stability.</p>
+<p><img src="https://media.forgecdn.net/attachments/1/banner.png" alt="Banner" width="600"></p>
+<ul>
+<li>Search items</li>
+<li>View <a href="https://www.curseforge.com/linkout?remoteUrl=https%253a%252f%252fgithub.com%252fmezz%252fJustEnoughItems">the source</a>
+<ul><li>Nested item</li></ul>
+</li>
+</ul>
+<ol><li>First</li><li>Second</li></ol>
+<table><tr><th>Version</th><th>Loader</th></tr><tr><td>1.20.1</td><td>Forge | NeoForge</td></tr></table>
+<blockquote><p>Quote line</p></blockquote>
+` + "<pre>code  block\n  indented</pre>" + `
+<p>Use <code>/jei</code> and see https://example.com/plain_url</p>
+<iframe src="https://www.youtube.com/embed/abc123"></iframe>
+<script>alert("x")</script>`
+
+func TestHTMLToMarkdown(t *testing.T) {
+markdown, err := HTMLToMarkdown(testDescriptionHTML)
+if err != nil {
+t.Fatalf("HTMLToMarkdown failed: %v", err)
+}
+
+expected := "## About **JEI**\n\n" +
+"JEI is an _item_ and recipe viewing mod.  \nIt is built from the ground up for stability.\n\n" +
+"![Banner](https://media.forgecdn.net/attachments/1/banner.png)\n\n" +
+"- Search items\n" +
+"- View [the source](https://github.com/mezz/JustEnoughItems)\n" +
+"  - Nested item\n\n" +
+"1. First\n" +
+"2. Second\n\n" +
+"| Version | Loader |\n" +
+"| --- | --- |\n" +
+"| 1.20.1 | Forge \\| NeoForge |\n\n" +
+"> Quote line\n\n" +
+"```\ncode  block\n  indented\n```\n\n" +
+"Use `/jei` and see https://example.com/plain\\_url\n\n" +
+"[Embedded video](https://www.youtube.com/embed/abc123)"
+if markdown != expected {
+t.Errorf("HTMLToMarkdown() =\n%s\n\nwant\n%s", markdown, expected)
+}
+}
+
+func TestHTMLToText(t *testing.T) {
+text, err := HTMLToText(`<p>Download <a href="/linkout?remoteUrl=https%253a%252f%252fexample.com">here</a>&nbsp;or <a href="https://example.com/wiki">https://example.com/wiki</a>.</p><p><img src="x.png" alt="Logo"><img src="y.png"></p><h1>Title</h1><ul><li>One</li><li><b>Two</b></li></ul>`)
+if err != nil {
+t.Fatalf("HTMLToText failed: %v", err)
+}
+
+expected := "Download here (https://example.com) or https://example.com/wiki.\n\n[Logo]\n\nTitle\n\n- One\n- Two"
+if text != expected {
+t.Errorf("HTMLToText() =\n%q\nwant\n%q", text, expected)
+}
+}
+
+func TestExtractLinksAndImages(t *testing.T) {
+links, err := ExtractLinks(testDescriptionHTML + `<a href="https://github.com/mezz/JustEnoughItems">again</a><a href="#top">top</a>`)
+if err != nil {
+t.Fatalf("ExtractLinks failed: %v", err)
+}
+expectedLinks := []HTMLLink{
+{URL: "https://github.com/mezz/JustEnoughItems", Text: "the source"},
+{URL: "https://www.youtube.com/embed/abc123"},
+{URL: "#top", Text: "top"},
+}
+if len(links) != len(expectedLinks) {
+t.Fatalf("ExtractLinks() = %+v", links)
+}
+for i, link := range expectedLinks {
+if links[i] != link {
+t.Errorf("links[%d] = %+v, want %+v", i, links[i], link)
+}
+}
+
+images, err := ExtractImages(testDescriptionHTML)
+if err != nil {
+t.Fatalf("ExtractImages failed: %v", err)
+}
+if len(images) != 1 || images[0].URL != "https://media.forgecdn.net/attachments/1/banner.png" || images[0].Alt != "Banner" {
+t.Errorf("ExtractImages() = %+v", images)
+}
+}
+
+func TestUnwrapLinkoutURL(t *testing.T) {
+tests := []struct {
+input    string
+expected string
+}{
+{
+input:    "https://www.curseforge.com/linkout?remoteUrl=https%253a%252f%252fdiscord.gg%252fabc",
+expected: "https://discord.gg/abc",
+},
+{
+input:    "/linkout?remoteUrl=https%3a%2f%2fexample.com%2fa%3fb%3dc",
+expected: "https://example.com/a?b=c",
+},
+{
+input:    "/linkout?remoteUrl=https%3a%2f%2fexample.com%2fsearch%3fq%3da%2526b%26x%3d1",
+expected: "https://example.com/search?q=a%26b&x=1",
+},
+{
+input:    "/linkout?remoteUrl=https%253a%252f%252fexample.com%252fsearch%253fq%253da%252526b%2526x%253d1",
+expected: "https://example.com/search?q=a%26b&x=1",
+},
+{
+input:    "/linkout?remoteUrl=https%3a%2f%2fexample.com%2fwiki%2fa%252Fb",
+expected: "https://example.com/wiki/a%2Fb",
+},
+{
+input:    "https://github.com/mezz/JustEnoughItems",
+expected: "https://github.com/mezz/JustEnoughItems",
+},
+{
+input:    "https://www.curseforge.com/linkout",
+expected: "https://www.curseforge.com/linkout",
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.input, func(t *testing.T) {
+if result := UnwrapLinkoutURL(tt.input); result != tt.expected {
+t.Errorf("UnwrapLinkoutURL() = %q, want %q", result, tt.expected)
+}
+})
+}
+}
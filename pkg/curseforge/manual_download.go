@@ -0,0 +1,187 @@
+package curseforge
+
+import (
+"errors"
+"fmt"
+"os"
+"path/filepath"
+"strings"
+)
+
+// curseForgeProjectURL redirects to a project page given only its ID
+const curseForgeProjectURL = "https://www.curseforge.com/projects"
+
+// ManualDownloadRequired describes a file that cannot be downloaded by third
+// party tools because its author disallowed distribution (Mod.AllowModDistribution)
+// The user has to download it from FileURL themselves
+// It implements error and matches ErrDownloadURLUnavailable with errors.Is
+type ManualDownloadRequired struct {
+ModID       int
+ModName     string
+FileID      int
+FileName    string
+ModURL      string // project page
+FileURL     string // file page with the download button
+FileLength  int64
+Fingerprint int64
+Sha1        string
+Destination string // where the file should be installed, if known
+}
+
+func (m *ManualDownloadRequired) Error() string {
+return fmt.Sprintf("%s (file %d) must be downloaded manually from %s", m.FileName, m.FileID, m.FileURL)
+}
+
+// Unwrap allows errors.Is(err, ErrDownloadURLUnavailable)
+func (m *ManualDownloadRequired) Unwrap() error {
+return ErrDownloadURLUnavailable
+}
+
+// NewManualDownloadRequired describes file as requiring a manual download
+// mod is optional; without it the URLs use the project ID redirect
+func NewManualDownloadRequired(file File, mod *Mod, destination string) *ManualDownloadRequired {
+manual := &ManualDownloadRequired{
+ModID:       file.ModID,
+FileID:      file.ID,
+FileName:    file.FileName,
+ModURL:      fmt.Sprintf("%s/%d", curseForgeProjectURL, file.ModID),
+FileLength:  file.FileLength,
+Fingerprint: file.FileFingerprint,
+Sha1:        GetSha1Hash(file),
+Destination: destination,
+}
+if mod != nil {
+manual.ModName = mod.Name
+if mod.Links.WebsiteURL != "" {
+manual.ModURL = strings.TrimSuffix(mod.Links.WebsiteURL, "/")
+}
+}
+manual.FileURL = fmt.Sprintf("%s/files/%d", manual.ModURL, file.ID)
+return manual
+}
+
+// AsManualDownloadRequired returns the ManualDownloadRequired carried by err, if any
+func AsManualDownloadRequired(err error) (*ManualDownloadRequired, bool) {
+var manual *ManualDownloadRequired
+if errors.As(err, &manual) {
+return manual, true
+}
+return nil, false
+}
+
+// CheckManualDownloads is a pre-flight check listing the requests whose files
+// have no download URL, with the mod and file page URLs filled in via GetMods
+func CheckManualDownloads(server CurseForgeServer, requests []DownloadRequest) ([]*ManualDownloadRequired, error) {
+var pending []DownloadRequest
+var modIDs []int
+seen := make(map[int]bool)
+for _, request := range requests {
+if request.File.DownloadURL != "" {
+continue
+}
+pending = append(pending, request)
+if !seen[request.File.ModID] {
+seen[request.File.ModID] = true
+modIDs = append(modIDs, request.File.ModID)
+}
+}
+if len(pending) == 0 {
+return nil, nil
+}
+
+mods, err := GetMods(server, modIDs)
+if err != nil {
+return nil, fmt.Errorf("failed to get mods for manual downloads: %w", err)
+}
+return ManualDownloadsFor(pending, mods), nil
+}
+
+// ManualDownloadsFor lists the requests without a download URL using already
+// fetched mods for the page URLs
+func ManualDownloadsFor(requests []DownloadRequest, mods []Mod) []*ManualDownloadRequired {
+modsByID := make(map[int]*Mod, len(mods))
+for i := range mods {
+modsByID[mods[i].ID] = &mods[i]
+}
+
+var manual []*ManualDownloadRequired
+for _, request := range requests {
+if request.File.DownloadURL == "" {
+manual = append(manual, NewManualDownloadRequired(request.File, modsByID[request.File.ModID], request.Destination))
+}
+}
+return manual
+}
+
+// ManualDownloadMatch pairs a pending manual download with the local file found for it
+type ManualDownloadMatch struct {
+Required *ManualDownloadRequired
+Path     string
+}
+
+// Install copies the found file to the destination of the manual download
+func (m ManualDownloadMatch) Install() error {
+return m.InstallTo(OSFS{})
+}
+
+// InstallTo copies the found file from the local watch folder to the
+// destination of the manual download on fsys
+func (m ManualDownloadMatch) InstallTo(fsys WritableFS) error {
+if m.Required.Destination == "" {
+return fmt.Errorf("no destination known for %s", m.Required.FileName)
+}
+return copyFileFS(OSFS{}, m.Path, fsys, m.Required.Destination)
+}
+
+// FindManualDownloads looks for pending manual downloads in watchDir (e.g. the
+// user's Downloads folder) by fingerprint, so renamed files are still found
+// Only files whose size matches a pending FileLength are hashed when the length is known
+// It returns the matches and the downloads still missing
+func FindManualDownloads(watchDir string, pending []*ManualDownloadRequired) ([]ManualDownloadMatch, []*ManualDownloadRequired, error) {
+entries, err := os.ReadDir(watchDir)
+if err != nil {
+return nil, nil, fmt.Errorf("failed to read watch folder: %w", err)
+}
+
+sizes := make(map[int64]bool)
+anySize := false
+for _, manual := range pending {
+if manual.FileLength > 0 {
+sizes[manual.FileLength] = true
+} else {
+anySize = true
+}
+}
+
+pathByFingerprint := make(map[int64]string)
+for _, entry := range entries {
+if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), DownloadPartSuffix) {
+continue
+}
+info, err := entry.Info()
+if err != nil || (!anySize && !sizes[info.Size()]) {
+continue
+}
+
+path := filepath.Join(watchDir, entry.Name())
+fingerprint, err := ComputeFileFingerprint(path)
+if err != nil {
+contextLogger.Trace(fmt.Sprintf("skipping %s: %v", path, err))
+continue
+}
+if _, ok := pathByFingerprint[fingerprint]; !ok {
+pathByFingerprint[fingerprint] = path
+}
+}
+
+var matches []ManualDownloadMatch
+var missing []*ManualDownloadRequired
+for _, manual := range pending {
+if path, ok := pathByFingerprint[manual.Fingerprint]; ok && manual.Fingerprint != 0 {
+matches = append(matches, ManualDownloadMatch{Required: manual, Path: path})
+} else {
+missing = append(missing, manual)
+}
+}
+return matches, missing, nil
+}
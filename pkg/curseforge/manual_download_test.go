@@ -0,0 +1,76 @@
+package curseforge
+
+import (
+"bytes"
+"errors"
+"os"
+"path/filepath"
+"testing"
+)
+
+func TestDownloadFileManualDownloadRequired(t *testing.T) {
+destination := filepath.Join(t.TempDir(), "mod.jar")
+file := File{ID: 4567, ModID: 1234, FileName: "restricted.jar"}
+
+err := DownloadFile(file, destination)
+manual, ok := AsManualDownloadRequired(err)
+if !ok {
+t.Fatalf("expected ManualDownloadRequired, got %v", err)
+}
+if !errors.Is(err, ErrDownloadURLUnavailable) {
+t.Error("ManualDownloadRequired does not match ErrDownloadURLUnavailable")
+}
+if manual.FileURL != "https://www.curseforge.com/projects/1234/files/4567" || manual.Destination != destination {
+t.Errorf("manual = %+v", manual)
+}
+if _, err := os.Stat(destination + DownloadPartSuffix); !os.IsNotExist(err) {
+t.Error("partial file created for a manual download")
+}
+}
+
+func TestManualDownloadsFor(t *testing.T) {
+requests := []DownloadRequest{
+{File: File{ID: 1, ModID: 10, DownloadURL: "https://edge.forgecdn.net/files/1/a.jar"}, Destination: "mods/a.jar"},
+{File: File{ID: 2, ModID: 20, FileName: "b.jar"}, Destination: "mods/b.jar"},
+}
+mods := []Mod{{ID: 20, Name: "B", Links: ModLinks{WebsiteURL: "https://www.curseforge.com/minecraft/mc-mods/b/"}}}
+
+manual := ManualDownloadsFor(requests, mods)
+if len(manual) != 1 {
+t.Fatalf("ManualDownloadsFor returned %d entries, want 1", len(manual))
+}
+if manual[0].ModName != "B" || manual[0].FileURL != "https://www.curseforge.com/minecraft/mc-mods/b/files/2" {
+t.Errorf("manual = %+v", manual[0])
+}
+}
+
+func TestFindManualDownloads(t *testing.T) {
+watchDir := t.TempDir()
+instanceDir := t.TempDir()
+
+wanted := fingerprintTestData(1500)
+os.WriteFile(filepath.Join(watchDir, "renamed-by-browser (1).jar"), wanted, 0o644)
+os.WriteFile(filepath.Join(watchDir, "unrelated.jar"), fingerprintTestData(1500)[1:], 0o644)
+
+found := &ManualDownloadRequired{FileID: 1, FileName: "wanted.jar", FileLength: int64(len(wanted)),
+Fingerprint: ComputeFingerprint(wanted), Destination: filepath.Join(instanceDir, "mods", "wanted.jar")}
+missing := &ManualDownloadRequired{FileID: 2, FileName: "other.jar", FileLength: 99, Fingerprint: 12345}
+
+matches, remaining, err := FindManualDownloads(watchDir, []*ManualDownloadRequired{found, missing})
+if err != nil {
+t.Fatalf("FindManualDownloads failed: %v", err)
+}
+if len(matches) != 1 || matches[0].Required != found || filepath.Base(matches[0].Path) != "renamed-by-browser (1).jar" {
+t.Fatalf("matches = %+v", matches)
+}
+if len(remaining) != 1 || remaining[0] != missing {
+t.Errorf("remaining = %+v", remaining)
+}
+
+if err := matches[0].Install(); err != nil {
+t.Fatalf("Install failed: %v", err)
+}
+if data, _ := os.ReadFile(found.Destination); !bytes.Equal(data, wanted) {
+t.Error("installed file content differs")
+}
+}
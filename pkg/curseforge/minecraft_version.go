@@ -0,0 +1,565 @@
+package curseforge
+
+import (
+"crypto/sha1"
+"encoding/hex"
+"encoding/json"
+"fmt"
+"io"
+"net/http"
+"path"
+"regexp"
+"runtime"
+"strings"
+"time"
+)
+
+// Minecraft version JSON support
+// MinecraftVersionInfo.JSONDownloadURL points at the launcher metadata for a
+// version. The types below model that document so the files a version needs
+// (client jar, libraries, natives, asset index, logging config) can be computed.
+
+// MetadataFetcher retrieves the raw body found at a URL
+// Replace it with a function reading fixtures or a local mirror for offline use
+type MetadataFetcher func(url string) ([]byte, error)
+
+// HTTPMetadataFetcher fetches metadata over HTTP using the default client
+func HTTPMetadataFetcher(url string) ([]byte, error) {
+resp, err := http.Get(url)
+if err != nil {
+return nil, err
+}
+defer resp.Body.Close()
+
+if resp.StatusCode != http.StatusOK {
+return nil, fmt.Errorf("metadata request failed with status: %d", resp.StatusCode)
+}
+
+data, err := io.ReadAll(resp.Body)
+if err != nil {
+return nil, fmt.Errorf("failed to read metadata body: %w", err)
+}
+return data, nil
+}
+
+// MinecraftVersionJSON represents the launcher version JSON for a Minecraft version
+type MinecraftVersionJSON struct {
+ID                     string                     `json:"id"`
+Type                   string                     `json:"type"`
+InheritsFrom           string                     `json:"inheritsFrom,omitempty"`
+MainClass              string                     `json:"mainClass"`
+Arguments              *VersionArguments          `json:"arguments,omitempty"`
+MinecraftArguments     string                     `json:"minecraftArguments,omitempty"`
+AssetIndex             AssetIndexRef              `json:"assetIndex"`
+Assets                 string                     `json:"assets"`
+ComplianceLevel        int                        `json:"complianceLevel"`
+JavaVersion            *VersionJavaVersion        `json:"javaVersion,omitempty"`
+Downloads              map[string]VersionDownload `json:"downloads"`
+Libraries              []VersionLibrary           `json:"libraries"`
+Logging                map[string]VersionLogging  `json:"logging,omitempty"`
+MinimumLauncherVersion int                        `json:"minimumLauncherVersion"`
+ReleaseTime            time.Time                  `json:"releaseTime"`
+Time                   time.Time                  `json:"time"`
+}
+
+// Download keys used in MinecraftVersionJSON.Downloads
+const (
+VersionDownloadClient         = "client"
+VersionDownloadClientMappings = "client_mappings"
+VersionDownloadServer         = "server"
+VersionDownloadServerMappings = "server_mappings"
+)
+
+// VersionDownload represents a downloadable file referenced by a version JSON
+type VersionDownload struct {
+Path string `json:"path,omitempty"`
+Sha1 string `json:"sha1"`
+Size int64  `json:"size"`
+URL  string `json:"url"`
+}
+
+// AssetIndexRef points at the asset index used by a version
+type AssetIndexRef struct {
+ID        string `json:"id"`
+Sha1      string `json:"sha1"`
+Size      int64  `json:"size"`
+TotalSize int64  `json:"totalSize"`
+URL       string `json:"url"`
+}
+
+// VersionJavaVersion represents the Java runtime a version requires
+type VersionJavaVersion struct {
+Component    string `json:"component"`
+MajorVersion int    `json:"majorVersion"`
+}
+
+// VersionLogging represents the logging configuration for one side
+type VersionLogging struct {
+Argument string         `json:"argument"`
+File     VersionLogFile `json:"file"`
+Type     string         `json:"type"`
+}
+
+// VersionLogFile represents a logging configuration file
+type VersionLogFile struct {
+ID   string `json:"id"`
+Sha1 string `json:"sha1"`
+Size int64  `json:"size"`
+URL  string `json:"url"`
+}
+
+// VersionLibrary represents a library entry in a version JSON
+type VersionLibrary struct {
+Name      string                   `json:"name"`
+Downloads *VersionLibraryDownloads `json:"downloads,omitempty"`
+URL       string                   `json:"url,omitempty"`
+Rules     []VersionRule            `json:"rules,omitempty"`
+Natives   map[string]string        `json:"natives,omitempty"`
+Extract   *VersionLibraryExtract   `json:"extract,omitempty"`
+}
+
+// VersionLibraryDownloads holds the artifact and native classifiers of a library
+type VersionLibraryDownloads struct {
+Artifact    *VersionDownload           `json:"artifact,omitempty"`
+Classifiers map[string]VersionDownload `json:"classifiers,omitempty"`
+}
+
+// VersionLibraryExtract lists paths excluded when extracting natives
+type VersionLibraryExtract struct {
+Exclude []string `json:"exclude"`
+}
+
+// VersionRule represents an allow/disallow rule on a library or argument
+type VersionRule struct {
+Action   string          `json:"action"`
+OS       *VersionRuleOS  `json:"os,omitempty"`
+Features map[string]bool `json:"features,omitempty"`
+}
+
+// VersionRuleOS represents the operating system condition of a rule
+type VersionRuleOS struct {
+Name    string `json:"name,omitempty"`
+Version string `json:"version,omitempty"`
+Arch    string `json:"arch,omitempty"`
+}
+
+// VersionArguments holds the modern game and JVM argument lists
+type VersionArguments struct {
+Game []VersionArgument `json:"game"`
+JVM  []VersionArgument `json:"jvm"`
+}
+
+// VersionArgument is a single argument entry, either a plain string or a
+// rule-guarded value holding one or more strings
+type VersionArgument struct {
+Rules  []VersionRule
+Values []string
+}
+
+// UnmarshalJSON decodes both the plain string and the {rules, value} forms
+func (a *VersionArgument) UnmarshalJSON(data []byte) error {
+var plain string
+if err := json.Unmarshal(data, &plain); err == nil {
+a.Rules = nil
+a.Values = []string{plain}
+return nil
+}
+
+var guarded struct {
+Rules []VersionRule   `json:"rules"`
+Value json.RawMessage `json:"value"`
+}
+if err := json.Unmarshal(data, &guarded); err != nil {
+return fmt.Errorf("failed to unmarshal argument: %w", err)
+}
+a.Rules = guarded.Rules
+
+var single string
+if err := json.Unmarshal(guarded.Value, &single); err == nil {
+a.Values = []string{single}
+return nil
+}
+var multiple []string
+if err := json.Unmarshal(guarded.Value, &multiple); err != nil {
+return fmt.Errorf("failed to unmarshal argument value: %w", err)
+}
+a.Values = multiple
+return nil
+}
+
+// MarshalJSON encodes the argument in the same form the launcher uses
+func (a VersionArgument) MarshalJSON() ([]byte, error) {
+if len(a.Rules) == 0 && len(a.Values) == 1 {
+return json.Marshal(a.Values[0])
+}
+guarded := struct {
+Rules []VersionRule `json:"rules"`
+Value interface{}   `json:"value"`
+}{Rules: a.Rules, Value: a.Values}
+if len(a.Values) == 1 {
+guarded.Value = a.Values[0]
+}
+return json.Marshal(guarded)
+}
+
+// RuleContext describes the environment rules are evaluated against
+type RuleContext struct {
+OSName    string          // "windows", "osx" or "linux"
+OSVersion string          // matched against the rule's version regex
+Arch      string          // "x86", "x86_64" or "arm64"
+Features  map[string]bool // launcher features such as is_demo_user
+}
+
+// CurrentRuleContext returns a RuleContext for the running platform
+func CurrentRuleContext() RuleContext {
+ctx := RuleContext{OSName: runtime.GOOS, Arch: runtime.GOARCH}
+switch runtime.GOOS {
+case "darwin":
+ctx.OSName = "osx"
+}
+switch runtime.GOARCH {
+case "386":
+ctx.Arch = "x86"
+case "amd64":
+ctx.Arch = "x86_64"
+}
+return ctx
+}
+
+// archBits returns the pointer width used to expand ${arch} in native classifiers
+func (c RuleContext) archBits() string {
+switch c.Arch {
+case "x86", "arm":
+return "32"
+default:
+return "64"
+}
+}
+
+// matches reports whether a single rule's conditions apply to the context
+func (r VersionRule) matches(ctx RuleContext) bool {
+if r.OS != nil {
+if r.OS.Name != "" && r.OS.Name != ctx.OSName {
+return false
+}
+if r.OS.Arch != "" && r.OS.Arch != ctx.Arch {
+return false
+}
+if r.OS.Version != "" {
+re, err := regexp.Compile(r.OS.Version)
+if err != nil || !re.MatchString(ctx.OSVersion) {
+return false
+}
+}
+}
+for feature, want := range r.Features {
+if ctx.Features[feature] != want {
+return false
+}
+}
+return true
+}
+
+// EvaluateRules applies launcher rule semantics: no rules means allowed,
+// otherwise the last matching rule decides and the default is disallowed
+func EvaluateRules(rules []VersionRule, ctx RuleContext) bool {
+if len(rules) == 0 {
+return true
+}
+
+allowed := false
+for _, rule := range rules {
+if rule.matches(ctx) {
+allowed = rule.Action == "allow"
+}
+}
+return allowed
+}
+
+// IsAllowed reports whether the library applies to the given context
+func (l VersionLibrary) IsAllowed(ctx RuleContext) bool {
+return EvaluateRules(l.Rules, ctx)
+}
+
+// NativeClassifier returns the natives classifier for the context, if any
+func (l VersionLibrary) NativeClassifier(ctx RuleContext) string {
+classifier, ok := l.Natives[ctx.OSName]
+if !ok {
+return ""
+}
+return strings.ReplaceAll(classifier, "${arch}", ctx.archBits())
+}
+
+// ArtifactPath returns the maven-style path of the library relative to the
+// libraries directory, derived from its name when no download path is given
+func (l VersionLibrary) ArtifactPath() string {
+if l.Downloads != nil && l.Downloads.Artifact != nil && l.Downloads.Artifact.Path != "" {
+return l.Downloads.Artifact.Path
+}
+return mavenPath(l.Name, "")
+}
+
+// mavenPath converts group:artifact:version[:classifier][@ext] into a repository path
+func mavenPath(name string, classifier string) string {
+ext := "jar"
+if idx := strings.Index(name, "@"); idx >= 0 {
+ext = name[idx+1:]
+name = name[:idx]
+}
+
+parts := strings.Split(name, ":")
+if len(parts) < 3 {
+return ""
+}
+group, artifact, version := parts[0], parts[1], parts[2]
+if classifier == "" && len(parts) > 3 {
+classifier = parts[3]
+}
+
+fileName := fmt.Sprintf("%s-%s", artifact, version)
+if classifier != "" {
+fileName = fmt.Sprintf("%s-%s", fileName, classifier)
+}
+return path.Join(strings.ReplaceAll(group, ".", "/"), artifact, version, fmt.Sprintf("%s.%s", fileName, ext))
+}
+
+// ResolvedArguments holds the argument lists that apply to a context
+type ResolvedArguments struct {
+Game []string
+JVM  []string
+}
+
+// ResolveArguments returns the game and JVM arguments that apply to the context
+// Legacy versions only carry minecraftArguments, which has no JVM arguments
+func (v *MinecraftVersionJSON) ResolveArguments(ctx RuleContext) ResolvedArguments {
+var resolved ResolvedArguments
+
+if v.Arguments == nil {
+if v.MinecraftArguments != "" {
+resolved.Game = strings.Fields(v.MinecraftArguments)
+}
+return resolved
+}
+
+for _, arg := range v.Arguments.Game {
+if EvaluateRules(arg.Rules, ctx) {
+resolved.Game = append(resolved.Game, arg.Values...)
+}
+}
+for _, arg := range v.Arguments.JVM {
+if EvaluateRules(arg.Rules, ctx) {
+resolved.JVM = append(resolved.JVM, arg.Values...)
+}
+}
+return resolved
+}
+
+// VersionFileKind identifies what a VersionFile is used for
+type VersionFileKind string
+
+const (
+VersionFileClient     VersionFileKind = "client"
+VersionFileServer     VersionFileKind = "server"
+VersionFileLibrary    VersionFileKind = "library"
+VersionFileNative     VersionFileKind = "native"
+VersionFileAssetIndex VersionFileKind = "assetIndex"
+VersionFileLogging    VersionFileKind = "logging"
+VersionFileAsset      VersionFileKind = "asset"
+)
+
+// VersionFile represents a file needed to run a version
+// Path is relative to the game directory (e.g. libraries/..., assets/...)
+type VersionFile struct {
+Kind VersionFileKind
+Path string
+URL  string
+Sha1 string
+Size int64
+}
+
+// RequiredFiles returns the client jar, libraries, natives, asset index and
+// logging config needed to launch the client in the given context
+// Asset objects are listed separately by AssetIndex.Files once the index is fetched
+func (v *MinecraftVersionJSON) RequiredFiles(ctx RuleContext) []VersionFile {
+var files []VersionFile
+
+if client, ok := v.Downloads[VersionDownloadClient]; ok {
+files = append(files, VersionFile{
+Kind: VersionFileClient,
+Path: path.Join("versions", v.ID, v.ID+".jar"),
+URL:  client.URL,
+Sha1: client.Sha1,
+Size: client.Size,
+})
+}
+
+for _, lib := range v.Libraries {
+if !lib.IsAllowed(ctx) {
+continue
+}
+
+if lib.Downloads != nil && lib.Downloads.Artifact != nil {
+artifact := lib.Downloads.Artifact
+files = append(files, VersionFile{
+Kind: VersionFileLibrary,
+Path: path.Join("libraries", lib.ArtifactPath()),
+URL:  artifact.URL,
+Sha1: artifact.Sha1,
+Size: artifact.Size,
+})
+} else if lib.Downloads == nil && lib.URL != "" {
+// Loader profiles list maven coordinates with a repository URL only
+libPath := lib.ArtifactPath()
+files = append(files, VersionFile{
+Kind: VersionFileLibrary,
+Path: path.Join("libraries", libPath),
+URL:  strings.TrimSuffix(lib.URL, "/") + "/" + libPath,
+})
+}
+
+if classifier := lib.NativeClassifier(ctx); classifier != "" && lib.Downloads != nil {
+if native, ok := lib.Downloads.Classifiers[classifier]; ok {
+nativePath := native.Path
+if nativePath == "" {
+nativePath = mavenPath(lib.Name, classifier)
+}
+files = append(files, VersionFile{
+Kind: VersionFileNative,
+Path: path.Join("libraries", nativePath),
+URL:  native.URL,
+Sha1: native.Sha1,
+Size: native.Size,
+})
+}
+}
+}
+
+if v.AssetIndex.URL != "" {
+files = append(files, VersionFile{
+Kind: VersionFileAssetIndex,
+Path: path.Join("assets", "indexes", v.AssetIndex.ID+".json"),
+URL:  v.AssetIndex.URL,
+Sha1: v.AssetIndex.Sha1,
+Size: v.AssetIndex.Size,
+})
+}
+
+if logging, ok := v.Logging[VersionDownloadClient]; ok && logging.File.URL != "" {
+files = append(files, VersionFile{
+Kind: VersionFileLogging,
+Path: path.Join("assets", "log_configs", logging.File.ID),
+URL:  logging.File.URL,
+Sha1: logging.File.Sha1,
+Size: logging.File.Size,
+})
+}
+
+return files
+}
+
+// ServerFile returns the dedicated server jar for the version, if published
+func (v *MinecraftVersionJSON) ServerFile() (*VersionFile, bool) {
+server, ok := v.Downloads[VersionDownloadServer]
+if !ok {
+return nil, false
+}
+return &VersionFile{
+Kind: VersionFileServer,
+Path: path.Join("versions", v.ID, v.ID+"-server.jar"),
+URL:  server.URL,
+Sha1: server.Sha1,
+Size: server.Size,
+}, true
+}
+
+// AssetIndex represents the asset index document referenced by AssetIndexRef
+type AssetIndex struct {
+Objects        map[string]AssetObject `json:"objects"`
+MapToResources bool                   `json:"map_to_resources,omitempty"`
+Virtual        bool                   `json:"virtual,omitempty"`
+}
+
+// AssetObject represents a single asset in an asset index
+type AssetObject struct {
+Hash string `json:"hash"`
+Size int64  `json:"size"`
+}
+
+// assetBaseURL is where asset objects are served from
+const assetBaseURL = "https://resources.download.minecraft.net"
+
+// Files returns the asset objects as VersionFiles stored under assets/objects
+func (a *AssetIndex) Files() []VersionFile {
+files := make([]VersionFile, 0, len(a.Objects))
+seen := make(map[string]bool, len(a.Objects))
+for _, object := range a.Objects {
+if len(object.Hash) < 2 || seen[object.Hash] {
+continue
+}
+seen[object.Hash] = true
+
+prefix := object.Hash[:2]
+files = append(files, VersionFile{
+Kind: VersionFileAsset,
+Path: path.Join("assets", "objects", prefix, object.Hash),
+URL:  fmt.Sprintf("%s/%s/%s", assetBaseURL, prefix, object.Hash),
+Sha1: object.Hash,
+Size: object.Size,
+})
+}
+return files
+}
+
+// ParseMinecraftVersionJSON parses a version JSON document
+func ParseMinecraftVersionJSON(data []byte) (*MinecraftVersionJSON, error) {
+var version MinecraftVersionJSON
+if err := json.Unmarshal(data, &version); err != nil {
+return nil, fmt.Errorf("failed to unmarshal version JSON: %w", err)
+}
+return &version, nil
+}
+
+// GetMinecraftVersionJSON downloads and parses the version JSON referenced by info
+func GetMinecraftVersionJSON(info MinecraftVersionInfo) (*MinecraftVersionJSON, error) {
+return FetchMinecraftVersionJSON(info, HTTPMetadataFetcher)
+}
+
+// FetchMinecraftVersionJSON retrieves the version JSON referenced by info using fetch
+func FetchMinecraftVersionJSON(info MinecraftVersionInfo, fetch MetadataFetcher) (*MinecraftVersionJSON, error) {
+if info.JSONDownloadURL == "" {
+return nil, fmt.Errorf("version JSON URL is not available for version %s", info.VersionString)
+}
+
+contextLogger.Trace(fmt.Sprintf("versionJSONUrl: %s", info.JSONDownloadURL))
+
+data, err := fetch(info.JSONDownloadURL)
+if err != nil {
+return nil, fmt.Errorf("failed to fetch version JSON: %w", err)
+}
+return ParseMinecraftVersionJSON(data)
+}
+
+// FetchAssetIndex retrieves and parses the asset index, verifying its SHA1 when known
+func FetchAssetIndex(ref AssetIndexRef, fetch MetadataFetcher) (*AssetIndex, error) {
+if ref.URL == "" {
+return nil, fmt.Errorf("asset index URL is not available for %s", ref.ID)
+}
+
+data, err := fetch(ref.URL)
+if err != nil {
+return nil, fmt.Errorf("failed to fetch asset index: %w", err)
+}
+
+if ref.Sha1 != "" {
+sum := sha1.Sum(data)
+if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, ref.Sha1) {
+return nil, fmt.Errorf("asset index %s sha1 mismatch: expected %s, got %s", ref.ID, ref.Sha1, actual)
+}
+}
+
+var index AssetIndex
+if err := json.Unmarshal(data, &index); err != nil {
+return nil, fmt.Errorf("failed to unmarshal asset index: %w", err)
+}
+return &index, nil
+}
@@ -0,0 +1,241 @@
+package curseforge
+
+import (
+"crypto/sha1"
+"encoding/hex"
+"fmt"
+"testing"
+)
+
+const testVersionJSON = `{
+"id": "1.20.1",
+"type": "release",
+"mainClass": "net.minecraft.client.main.Main",
+"arguments": {
+"game": ["--username", "${auth_player_name}", {"rules": [{"action": "allow", "features": {"is_demo_user": true}}], "value": "--demo"}],
+"jvm": [{"rules": [{"action": "allow", "os": {"name": "osx"}}], "value": ["-XstartOnFirstThread"]}, "-cp", "${classpath}"]
+},
+"assetIndex": {"id": "5", "sha1": "abc", "size": 10, "totalSize": 20, "url": "https://example.com/5.json"},
+"assets": "5",
+"javaVersion": {"component": "java-runtime-gamma", "majorVersion": 17},
+"downloads": {
+"client": {"sha1": "c1", "size": 100, "url": "https://example.com/client.jar"},
+"server": {"sha1": "s1", "size": 200, "url": "https://example.com/server.jar"}
+},
+"libraries": [
+{"name": "com.mojang:logging:0.1.1", "downloads": {"artifact": {"path": "com/mojang/logging/0.1.1/logging-0.1.1.jar", "sha1": "l1", "size": 1, "url": "https://example.com/logging.jar"}}},
+{"name": "ca.weblite:java-objc-bridge:1.1", "downloads": {"artifact": {"path": "ca/weblite/java-objc-bridge/1.1/java-objc-bridge-1.1.jar", "sha1": "l2", "size": 2, "url": "https://example.com/objc.jar"}}, "rules": [{"action": "allow", "os": {"name": "osx"}}]},
+{"name": "org.lwjgl:lwjgl-platform:2.9.4", "natives": {"linux": "natives-linux", "windows": "natives-windows-${arch}"}, "downloads": {"classifiers": {"natives-linux": {"path": "org/lwjgl/lwjgl-platform/2.9.4/lwjgl-platform-2.9.4-natives-linux.jar", "sha1": "n1", "size": 3, "url": "https://example.com/natives-linux.jar"}, "natives-windows-64": {"path": "org/lwjgl/lwjgl-platform/2.9.4/lwjgl-platform-2.9.4-natives-windows-64.jar", "sha1": "n2", "size": 4, "url": "https://example.com/natives-windows.jar"}}}},
+{"name": "net.fabricmc:fabric-loader:0.14.21", "url": "https://maven.fabricmc.net/"}
+],
+"logging": {"client": {"argument": "-Dlog4j.configurationFile=${path}", "file": {"id": "client-1.12.xml", "sha1": "x1", "size": 5, "url": "https://example.com/client-1.12.xml"}, "type": "log4j2-xml"}}
+}`
+
+func TestParseMinecraftVersionJSON(t *testing.T) {
+version, err := ParseMinecraftVersionJSON([]byte(testVersionJSON))
+if err != nil {
+t.Fatalf("ParseMinecraftVersionJSON failed: %v", err)
+}
+
+if version.MainClass != "net.minecraft.client.main.Main" {
+t.Errorf("MainClass = %q", version.MainClass)
+}
+if version.JavaVersion == nil || version.JavaVersion.MajorVersion != 17 {
+t.Errorf("JavaVersion = %+v, want major version 17", version.JavaVersion)
+}
+if len(version.Arguments.Game) != 3 || len(version.Arguments.JVM) != 3 {
+t.Fatalf("unexpected argument counts: game=%d jvm=%d", len(version.Arguments.Game), len(version.Arguments.JVM))
+}
+if got := version.Arguments.JVM[0].Values; len(got) != 1 || got[0] != "-XstartOnFirstThread" {
+t.Errorf("guarded JVM argument values = %q", got)
+}
+}
+
+func TestResolveArguments(t *testing.T) {
+version, err := ParseMinecraftVersionJSON([]byte(testVersionJSON))
+if err != nil {
+t.Fatalf("ParseMinecraftVersionJSON failed: %v", err)
+}
+
+linux := version.ResolveArguments(RuleContext{OSName: "linux", Arch: "x86_64"})
+if fmt.Sprint(linux.Game) != "[--username ${auth_player_name}]" {
+t.Errorf("linux game arguments = %q", linux.Game)
+}
+if fmt.Sprint(linux.JVM) != "[-cp ${classpath}]" {
+t.Errorf("linux JVM arguments = %q", linux.JVM)
+}
+
+demo := version.ResolveArguments(RuleContext{OSName: "osx", Features: map[string]bool{"is_demo_user": true}})
+if fmt.Sprint(demo.Game) != "[--username ${auth_player_name} --demo]" {
+t.Errorf("demo game arguments = %q", demo.Game)
+}
+if fmt.Sprint(demo.JVM) != "[-XstartOnFirstThread -cp ${classpath}]" {
+t.Errorf("osx JVM arguments = %q", demo.JVM)
+}
+
+legacy := MinecraftVersionJSON{MinecraftArguments: "--username ${auth_player_name} --version ${version_name}"}
+if got := legacy.ResolveArguments(RuleContext{}); len(got.Game) != 4 || got.JVM != nil {
+t.Errorf("legacy arguments = %+v", got)
+}
+}
+
+func TestEvaluateRules(t *testing.T) {
+tests := []struct {
+name     string
+rules    []VersionRule
+ctx      RuleContext
+expected bool
+}{
+{
+name:     "no rules",
+expected: true,
+},
+{
+name:     "allow matching os",
+rules:    []VersionRule{{Action: "allow", OS: &VersionRuleOS{Name: "osx"}}},
+ctx:      RuleContext{OSName: "osx"},
+expected: true,
+},
+{
+name:     "allow other os",
+rules:    []VersionRule{{Action: "allow", OS: &VersionRuleOS{Name: "osx"}}},
+ctx:      RuleContext{OSName: "linux"},
+expected: false,
+},
+{
+name:     "allow all but disallow os",
+rules:    []VersionRule{{Action: "allow"}, {Action: "disallow", OS: &VersionRuleOS{Name: "osx"}}},
+ctx:      RuleContext{OSName: "osx"},
+expected: false,
+},
+{
+name:     "os version regex",
+rules:    []VersionRule{{Action: "allow", OS: &VersionRuleOS{Name: "windows", Version: "^10\\."}}},
+ctx:      RuleContext{OSName: "windows", OSVersion: "10.0"},
+expected: true,
+},
+{
+name:     "arch mismatch",
+rules:    []VersionRule{{Action: "allow", OS: &VersionRuleOS{Arch: "x86"}}},
+ctx:      RuleContext{OSName: "windows", Arch: "x86_64"},
+expected: false,
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+if result := EvaluateRules(tt.rules, tt.ctx); result != tt.expected {
+t.Errorf("EvaluateRules() = %v, want %v", result, tt.expected)
+}
+})
+}
+}
+
+func TestRequiredFiles(t *testing.T) {
+version, err := ParseMinecraftVersionJSON([]byte(testVersionJSON))
+if err != nil {
+t.Fatalf("ParseMinecraftVersionJSON failed: %v", err)
+}
+
+tests := []struct {
+name     string
+ctx      RuleContext
+expected []string
+}{
+{
+name: "linux",
+ctx:  RuleContext{OSName: "linux", Arch: "x86_64"},
+expected: []string{
+"versions/1.20.1/1.20.1.jar",
+"libraries/com/mojang/logging/0.1.1/logging-0.1.1.jar",
+"libraries/org/lwjgl/lwjgl-platform/2.9.4/lwjgl-platform-2.9.4-natives-linux.jar",
+"libraries/net/fabricmc/fabric-loader/0.14.21/fabric-loader-0.14.21.jar",
+"assets/indexes/5.json",
+"assets/log_configs/client-1.12.xml",
+},
+},
+{
+name: "windows 64-bit natives",
+ctx:  RuleContext{OSName: "windows", Arch: "x86_64"},
+expected: []string{
+"versions/1.20.1/1.20.1.jar",
+"libraries/com/mojang/logging/0.1.1/logging-0.1.1.jar",
+"libraries/org/lwjgl/lwjgl-platform/2.9.4/lwjgl-platform-2.9.4-natives-windows-64.jar",
+"libraries/net/fabricmc/fabric-loader/0.14.21/fabric-loader-0.14.21.jar",
+"assets/indexes/5.json",
+"assets/log_configs/client-1.12.xml",
+},
+},
+{
+name: "osx only library",
+ctx:  RuleContext{OSName: "osx", Arch: "arm64"},
+expected: []string{
+"versions/1.20.1/1.20.1.jar",
+"libraries/com/mojang/logging/0.1.1/logging-0.1.1.jar",
+"libraries/ca/weblite/java-objc-bridge/1.1/java-objc-bridge-1.1.jar",
+"libraries/net/fabricmc/fabric-loader/0.14.21/fabric-loader-0.14.21.jar",
+"assets/indexes/5.json",
+"assets/log_configs/client-1.12.xml",
+},
+},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+files := version.RequiredFiles(tt.ctx)
+paths := make([]string, 0, len(files))
+for _, f := range files {
+paths = append(paths, f.Path)
+}
+if fmt.Sprint(paths) != fmt.Sprint(tt.expected) {
+t.Errorf("RequiredFiles() paths = %q, want %q", paths, tt.expected)
+}
+})
+}
+
+loader := version.RequiredFiles(RuleContext{OSName: "linux"})[3]
+if loader.URL != "https://maven.fabricmc.net/net/fabricmc/fabric-loader/0.14.21/fabric-loader-0.14.21.jar" {
+t.Errorf("maven library URL = %q", loader.URL)
+}
+}
+
+func TestFetchMinecraftVersionJSONWithFetcher(t *testing.T) {
+indexData := []byte(`{"objects": {"icons/icon_16x16.png": {"hash": "bdf48ef6b5d0d23bbb02e17d04865216179f510a", "size": 3665}}}`)
+indexSum := sha1.Sum(indexData)
+
+fetched := map[string][]byte{
+"https://example.com/1.20.1.json": []byte(testVersionJSON),
+"https://example.com/5.json":      indexData,
+}
+fetch := func(url string) ([]byte, error) {
+data, ok := fetched[url]
+if !ok {
+return nil, fmt.Errorf("unexpected url %s", url)
+}
+return data, nil
+}
+
+version, err := FetchMinecraftVersionJSON(MinecraftVersionInfo{VersionString: "1.20.1", JSONDownloadURL: "https://example.com/1.20.1.json"}, fetch)
+if err != nil {
+t.Fatalf("FetchMinecraftVersionJSON failed: %v", err)
+}
+
+ref := version.AssetIndex
+if _, err := FetchAssetIndex(ref, fetch); err == nil {
+t.Error("expected sha1 mismatch for asset index")
+}
+
+ref.Sha1 = hex.EncodeToString(indexSum[:])
+index, err := FetchAssetIndex(ref, fetch)
+if err != nil {
+t.Fatalf("FetchAssetIndex failed: %v", err)
+}
+files := index.Files()
+if len(files) != 1 || files[0].Path != "assets/objects/bd/bdf48ef6b5d0d23bbb02e17d04865216179f510a" {
+t.Errorf("asset files = %+v", files)
+}
+
+if _, err := FetchMinecraftVersionJSON(MinecraftVersionInfo{VersionString: "1.20.1"}, fetch); err == nil {
+t.Error("expected error for missing JSON URL")
+}
+}
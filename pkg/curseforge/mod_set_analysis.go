@@ -0,0 +1,165 @@
+package curseforge
+
+import "sort"
+
+// IncompatibleMods is a pair of installed files where at least one declares
+// the other's mod incompatible
+type IncompatibleMods struct {
+File     File // the file declaring the incompatibility
+Conflict File
+Mutual   bool // Conflict also declares File's mod incompatible
+}
+
+// MissingDependency is a required mod that is neither installed nor embedded
+type MissingDependency struct {
+ModID      int
+RequiredBy []File
+}
+
+// AvailableDependency is an optional mod that is not installed
+type AvailableDependency struct {
+ModID       int
+SuggestedBy []File
+}
+
+// DuplicateLibrary is a library embedded in several files, or embedded and
+// also installed on its own
+type DuplicateLibrary struct {
+ModID      int
+EmbeddedIn []File
+Installed  *File // the standalone file, nil when only embedded
+}
+
+// ModSetReport is the result of AnalyzeModSet
+// Every list is sorted by mod ID
+type ModSetReport struct {
+Incompatible        []IncompatibleMods
+MissingDependencies []MissingDependency
+OptionalAvailable   []AvailableDependency
+DuplicateLibraries  []DuplicateLibrary
+}
+
+// HasProblems reports whether the set has incompatibilities or missing dependencies
+// Optional dependencies and duplicate libraries are informational
+func (r *ModSetReport) HasProblems() bool {
+return len(r.Incompatible) > 0 || len(r.MissingDependencies) > 0
+}
+
+// InstalledFiles returns the files of the exact matches, e.g. for AnalyzeModSet
+// Files matched at several paths are returned once
+func (r *ScanReport) InstalledFiles() []File {
+seen := make(map[int]bool)
+var files []File
+for _, match := range r.Matches {
+if seen[match.File.ID] {
+continue
+}
+seen[match.File.ID] = true
+files = append(files, match.File)
+}
+sort.Slice(files, func(i, j int) bool { return files[i].ID < files[j].ID })
+return files
+}
+
+// AnalyzeModSet checks the dependencies declared by a set of installed files
+// against each other
+//
+// A required dependency counts as present when its mod is installed or
+// embedded in another installed file. The analysis uses only the declared
+// dependencies, so no API calls are made.
+func AnalyzeModSet(files []File) *ModSetReport {
+installed := make(map[int]File)
+embeddedIn := make(map[int][]File)
+for _, file := range files {
+installed[file.ModID] = file
+for _, dependency := range file.Dependencies {
+if dependency.RelationType == DependencyTypeEmbeddedLibrary {
+embeddedIn[dependency.ModID] = append(embeddedIn[dependency.ModID], file)
+}
+}
+}
+
+report := &ModSetReport{}
+missing := make(map[int][]File)
+optional := make(map[int][]File)
+reported := make(map[[2]int]bool)
+
+for _, file := range files {
+for _, dependency := range file.Dependencies {
+_, isInstalled := installed[dependency.ModID]
+switch dependency.RelationType {
+case DependencyTypeIncompatible:
+conflict, ok := installed[dependency.ModID]
+if !ok || conflict.ModID == file.ModID {
+continue
+}
+pair := [2]int{file.ModID, conflict.ModID}
+if pair[0] > pair[1] {
+pair[0], pair[1] = pair[1], pair[0]
+}
+if reported[pair] {
+continue
+}
+reported[pair] = true
+report.Incompatible = append(report.Incompatible, IncompatibleMods{
+File:     file,
+Conflict: conflict,
+Mutual:   declares(conflict, file.ModID, DependencyTypeIncompatible),
+})
+case DependencyTypeRequired:
+if !isInstalled && len(embeddedIn[dependency.ModID]) == 0 {
+missing[dependency.ModID] = append(missing[dependency.ModID], file)
+}
+case DependencyTypeOptional:
+if !isInstalled && len(embeddedIn[dependency.ModID]) == 0 {
+optional[dependency.ModID] = append(optional[dependency.ModID], file)
+}
+}
+}
+}
+
+for modID, requiredBy := range missing {
+report.MissingDependencies = append(report.MissingDependencies, MissingDependency{ModID: modID, RequiredBy: requiredBy})
+}
+for modID, suggestedBy := range optional {
+report.OptionalAvailable = append(report.OptionalAvailable, AvailableDependency{ModID: modID, SuggestedBy: suggestedBy})
+}
+for modID, embedders := range embeddedIn {
+standalone, isInstalled := installed[modID]
+if len(embedders) < 2 && !isInstalled {
+continue
+}
+duplicate := DuplicateLibrary{ModID: modID, EmbeddedIn: embedders}
+if isInstalled {
+duplicate.Installed = &standalone
+}
+report.DuplicateLibraries = append(report.DuplicateLibraries, duplicate)
+}
+
+sort.Slice(report.Incompatible, func(i, j int) bool {
+if report.Incompatible[i].File.ModID != report.Incompatible[j].File.ModID {
+return report.Incompatible[i].File.ModID < report.Incompatible[j].File.ModID
+}
+return report.Incompatible[i].Conflict.ModID < report.Incompatible[j].Conflict.ModID
+})
+sort.Slice(report.MissingDependencies, func(i, j int) bool {
+return report.MissingDependencies[i].ModID < report.MissingDependencies[j].ModID
+})
+sort.Slice(report.OptionalAvailable, func(i, j int) bool {
+return report.OptionalAvailable[i].ModID < report.OptionalAvailable[j].ModID
+})
+sort.Slice(report.DuplicateLibraries, func(i, j int) bool {
+return report.DuplicateLibraries[i].ModID < report.DuplicateLibraries[j].ModID
+})
+return report
+}
+
+// declares reports whether a file declares a dependency of the given type on a mod
+func declares(file File, modID int, relation DependencyType) bool {
+for _, dependency := range file.Dependencies {
+if dependency.ModID == modID && dependency.RelationType == relation {
+return true
+}
+}
+return false
+}
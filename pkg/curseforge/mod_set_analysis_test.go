@@ -0,0 +1,79 @@
+package curseforge
+
+import (
+"fmt"
+"testing"
+)
+
+func analysisTestFile(modID int, dependencies ...FileDependency) File {
+return File{ID: modID * 10, ModID: modID, Dependencies: dependencies}
+}
+
+func TestAnalyzeModSet(t *testing.T) {
+files := []File{
+analysisTestFile(1,
+FileDependency{ModID: 2, RelationType: DependencyTypeIncompatible},
+FileDependency{ModID: 9, RelationType: DependencyTypeRequired},
+FileDependency{ModID: 7, RelationType: DependencyTypeEmbeddedLibrary}),
+analysisTestFile(2,
+FileDependency{ModID: 1, RelationType: DependencyTypeIncompatible},
+FileDependency{ModID: 8, RelationType: DependencyTypeOptional},
+FileDependency{ModID: 7, RelationType: DependencyTypeRequired}),
+analysisTestFile(3,
+FileDependency{ModID: 4, RelationType: DependencyTypeIncompatible},
+FileDependency{ModID: 9, RelationType: DependencyTypeRequired},
+FileDependency{ModID: 6, RelationType: DependencyTypeEmbeddedLibrary}),
+analysisTestFile(4,
+FileDependency{ModID: 6, RelationType: DependencyTypeEmbeddedLibrary},
+FileDependency{ModID: 5, RelationType: DependencyTypeEmbeddedLibrary}),
+analysisTestFile(5),
+}
+
+report := AnalyzeModSet(files)
+
+if len(report.Incompatible) != 2 {
+t.Fatalf("Incompatible = %+v, want 2 pairs", report.Incompatible)
+}
+if pair := report.Incompatible[0]; pair.File.ModID != 1 || pair.Conflict.ModID != 2 || !pair.Mutual {
+t.Errorf("Incompatible[0] = %+v, want mutual 1-2", pair)
+}
+if pair := report.Incompatible[1]; pair.File.ModID != 3 || pair.Conflict.ModID != 4 || pair.Mutual {
+t.Errorf("Incompatible[1] = %+v, want one-sided 3-4", pair)
+}
+
+// 7 is embedded in mod 1, so mod 2's requirement is satisfied
+if len(report.MissingDependencies) != 1 || report.MissingDependencies[0].ModID != 9 || len(report.MissingDependencies[0].RequiredBy) != 2 {
+t.Errorf("MissingDependencies = %+v, want mod 9 required by 2 files", report.MissingDependencies)
+}
+if len(report.OptionalAvailable) != 1 || report.OptionalAvailable[0].ModID != 8 {
+t.Errorf("OptionalAvailable = %+v, want mod 8", report.OptionalAvailable)
+}
+
+var duplicates []string
+for _, duplicate := range report.DuplicateLibraries {
+duplicates = append(duplicates, fmt.Sprintf("%d:%d:%v", duplicate.ModID, len(duplicate.EmbeddedIn), duplicate.Installed != nil))
+}
+if fmt.Sprint(duplicates) != "[5:1:true 6:2:false]" {
+t.Errorf("DuplicateLibraries = %v", duplicates)
+}
+if !report.HasProblems() {
+t.Error("HasProblems() = false, want true")
+}
+
+if AnalyzeModSet([]File{analysisTestFile(5)}).HasProblems() {
+t.Error("a single file without dependencies should have no problems")
+}
+}
+
+func TestScanReportInstalledFiles(t *testing.T) {
+report := ScanReport{Matches: map[string]ScanMatch{
+"a.jar":      {File: File{ID: 2}},
+"b.jar":      {File: File{ID: 1}},
+"copy/b.jar": {File: File{ID: 1}},
+}}
+
+files := report.InstalledFiles()
+if len(files) != 2 || files[0].ID != 1 || files[1].ID != 2 {
+t.Errorf("InstalledFiles() = %+v, want files 1 and 2", files)
+}
+}
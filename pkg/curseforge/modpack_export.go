@@ -0,0 +1,292 @@
+package curseforge
+
+import (
+"archive/zip"
+"errors"
+"fmt"
+"html"
+"io"
+"io/fs"
+"os"
+"path"
+"path/filepath"
+"sort"
+"strings"
+)
+
+// ModpackModListFileName is the name of the HTML mod list written next to the manifest
+const ModpackModListFileName = "modlist.html"
+
+// DefaultExportContentDirs lists the instance folders whose files are matched on CurseForge
+var DefaultExportContentDirs = []string{"mods", "resourcepacks", "shaderpacks"}
+
+// DefaultExportOverrideDirs lists the instance folders copied to the overrides as is
+var DefaultExportOverrideDirs = []string{"config", "defaultconfigs", "kubejs", "scripts"}
+
+// ModpackExportOptions configures ExportModpack
+type ModpackExportOptions struct {
+// Manifest provides the name, version, author and Minecraft section of the
+// modpack; its files and overrides are replaced. Defaults to the
+// manifest.json of the instance directory.
+Manifest *ModpackManifest
+
+// ContentDirs are fingerprinted and matched, defaults to DefaultExportContentDirs
+ContentDirs []string
+
+// OverrideDirs are instance-relative folders or files copied to the
+// overrides, defaults to DefaultExportOverrideDirs; missing entries are skipped
+OverrideDirs []string
+
+// Scan configures the fingerprint matching; Recursive is always set
+Scan ScanOptions
+
+// FS is the filesystem the modpack zip is written to, defaults to OSFS
+FS WritableFS
+}
+
+// ModpackExport is the result of ExportModpack
+type ModpackExport struct {
+Manifest  *ModpackManifest
+Matched   []ScanMatch // files recorded in the manifest
+Overrides []string    // files written to the overrides, relative to the instance
+}
+
+// ExportModpack writes the instance in instanceDir as a CurseForge modpack zip
+//
+// Every file under the content directories is fingerprinted and matched with
+// ScanDirectory. Exact matches are recorded in the manifest; unmatched and
+// partially matched files, files of a project already listed and files whose
+// project class belongs in another folder go to the overrides with the
+// override directories. The zip holds manifest.json, modlist.html and the
+// overrides, and is written atomically to outputPath.
+func ExportModpack(server CurseForgeServer, instanceDir string, outputPath string, options ModpackExportOptions) (*ModpackExport, error) {
+manifest, err := exportManifest(instanceDir, options.Manifest)
+if err != nil {
+return nil, err
+}
+
+contentDirs := options.ContentDirs
+if len(contentDirs) == 0 {
+contentDirs = DefaultExportContentDirs
+}
+overrideDirs := options.OverrideDirs
+if len(overrideDirs) == 0 {
+overrideDirs = DefaultExportOverrideDirs
+}
+
+export := &ModpackExport{Manifest: manifest}
+var mods []*Mod
+var overrides []string // native paths
+for _, dir := range contentDirs {
+matched, unmatched, err := exportContentDir(server, instanceDir, dir, options.Scan)
+if err != nil {
+return nil, err
+}
+overrides = append(overrides, unmatched...)
+
+for _, match := range matched {
+folder := ClassFolder(ClassIDMods)
+if match.Mod != nil {
+folder = ClassFolder(match.Mod.ClassID)
+}
+if folder != dir || hasManifestProject(manifest, match.File.ModID) {
+overrides = append(overrides, match.Path)
+continue
+}
+manifest.AddFile(match.File.ModID, match.File.ID, true)
+export.Matched = append(export.Matched, match)
+mods = append(mods, match.Mod)
+}
+}
+
+for _, dir := range overrideDirs {
+files, err := listInstanceFiles(filepath.Join(instanceDir, filepath.FromSlash(dir)))
+if err != nil {
+return nil, err
+}
+overrides = append(overrides, files...)
+}
+
+if err := manifest.Validate(); err != nil {
+return nil, err
+}
+
+entries := make(map[string]string, len(overrides)) // zip path -> native path
+for _, file := range overrides {
+relative, err := filepath.Rel(instanceDir, file)
+if err != nil {
+return nil, fmt.Errorf("failed to export %s: %w", file, err)
+}
+relative = filepath.ToSlash(relative)
+if _, ok := entries[relative]; ok {
+continue
+}
+entries[relative] = file
+export.Overrides = append(export.Overrides, relative)
+}
+sort.Strings(export.Overrides)
+
+contextLogger.Trace(fmt.Sprintf("Exporting %s: %d files, %d overrides", manifest.Name, len(manifest.Files), len(export.Overrides)))
+
+err = writeFileFS(options.FS, outputPath, 0o644, func(w io.Writer) error {
+archive := zip.NewWriter(w)
+data, err := manifest.Encode()
+if err != nil {
+return err
+}
+if err := writeZipEntry(archive, ModpackManifestFileName, data); err != nil {
+return err
+}
+if err := writeZipEntry(archive, ModpackModListFileName, []byte(ModListHTML(mods))); err != nil {
+return err
+}
+for _, relative := range export.Overrides {
+if err := copyToZip(archive, path.Join(manifest.OverridesDir(), relative), entries[relative]); err != nil {
+return fmt.Errorf("failed to add %s: %w", relative, err)
+}
+}
+return archive.Close()
+})
+if err != nil {
+return nil, fmt.Errorf("failed to write modpack: %w", err)
+}
+return export, nil
+}
+
+// ModListHTML renders the modlist.html of a modpack, one link per mod
+// Mods without details are skipped
+func ModListHTML(mods []*Mod) string {
+var b strings.Builder
+b.WriteString("<ul>\n")
+for _, mod := range mods {
+if mod == nil {
+continue
+}
+url := strings.TrimSuffix(mod.Links.WebsiteURL, "/")
+if url == "" {
+url = fmt.Sprintf("%s/%d", curseForgeProjectURL, mod.ID)
+}
+label := mod.Name
+if len(mod.Authors) > 0 {
+label = fmt.Sprintf("%s (by %s)", mod.Name, mod.Authors[0].Name)
+}
+fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(url), html.EscapeString(label))
+}
+b.WriteString("</ul>\n")
+return b.String()
+}
+
+// exportManifest copies the template, or the instance's manifest.json, with its files cleared
+func exportManifest(instanceDir string, template *ModpackManifest) (*ModpackManifest, error) {
+if template == nil {
+read, err := ReadModpackManifest(OSFS{}, instanceDir)
+if err != nil {
+return nil, fmt.Errorf("no manifest given and none found in the instance: %w", err)
+}
+template = read
+}
+
+manifest := *template
+manifest.Minecraft.ModLoaders = append([]ManifestModLoader{}, template.Minecraft.ModLoaders...)
+manifest.Files = []ManifestFile{}
+manifest.Overrides = DefaultOverridesDir
+if manifest.ManifestType == "" {
+manifest.ManifestType = ModpackManifestType
+}
+if manifest.ManifestVersion == 0 {
+manifest.ManifestVersion = ModpackManifestVersion
+}
+return &manifest, nil
+}
+
+// exportContentDir matches the files of a content directory
+// It returns the exact matches sorted by path and every other file
+func exportContentDir(server CurseForgeServer, instanceDir string, dir string, scan ScanOptions) ([]ScanMatch, []string, error) {
+root := filepath.Join(instanceDir, filepath.FromSlash(dir))
+files, err := listInstanceFiles(root)
+if err != nil || len(files) == 0 {
+return nil, nil, err
+}
+
+scan.Recursive = true
+report, err := ScanDirectory(server, root, scan)
+if err != nil {
+return nil, nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+}
+var errs []error
+for file, err := range report.Errors {
+errs = append(errs, fmt.Errorf("%s: %w", file, err))
+}
+if len(errs) > 0 {
+return nil, nil, fmt.Errorf("failed to fingerprint %s: %w", dir, errors.Join(errs...))
+}
+
+var matched []ScanMatch
+var unmatched []string
+for _, file := range files {
+if match, ok := report.Matches[file]; ok {
+matched = append(matched, match)
+} else {
+unmatched = append(unmatched, file)
+}
+}
+return matched, unmatched, nil
+}
+
+// listInstanceFiles lists the regular files under root, which may be a single
+// file, in lexical order; a missing root has no files
+func listInstanceFiles(root string) ([]string, error) {
+var files []string
+err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+if err != nil {
+return err
+}
+if entry.Type().IsRegular() {
+files = append(files, path)
+}
+return nil
+})
+if errors.Is(err, fs.ErrNotExist) {
+return nil, nil
+}
+if err != nil {
+return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+}
+return files, nil
+}
+
+// hasManifestProject reports whether the manifest already lists a file of the project
+func hasManifestProject(manifest *ModpackManifest, projectID int) bool {
+for _, file := range manifest.Files {
+if file.ProjectID == projectID {
+return true
+}
+}
+return false
+}
+
+// writeZipEntry adds a file with the given content to a zip
+func writeZipEntry(archive *zip.Writer, name string, data []byte) error {
+w, err := archive.Create(name)
+if err != nil {
+return err
+}
+_, err = w.Write(data)
+return err
+}
+
+// copyToZip adds a local file to a zip
+func copyToZip(archive *zip.Writer, name string, source string) error {
+in, err := os.Open(source)
+if err != nil {
+return err
+}
+defer in.Close()
+
+w, err := archive.Create(name)
+if err != nil {
+return err
+}
+_, err = io.Copy(w, in)
+return err
+}
@@ -0,0 +1,160 @@
+package curseforge
+
+import (
+"archive/zip"
+"bytes"
+"encoding/json"
+"net/http"
+"net/http/httptest"
+"os"
+"path/filepath"
+"reflect"
+"sort"
+"strings"
+"testing"
+)
+
+func TestExportModpack(t *testing.T) {
+instanceDir := t.TempDir()
+files := map[string]string{
+"mods/jei.jar":                   "jei contents",
+"mods/jei-old.jar":               "old jei contents",
+"mods/local.jar":                 "local build",
+"mods/wrong-folder.zip":          "a resource pack in mods",
+"resourcepacks/faithful.zip":     "faithful contents",
+"shaderpacks/bsl.zip.txt":        "shader settings",
+"config/jei/jei-client.toml":     "[advanced]",
+"kubejs/server_scripts/main.js":  "// recipes",
+"saves/world/level.dat":          "not exported",
+"options.txt":                    "not exported",
+"manifest.json":                  mustEncodeManifest(t, NewModpackManifest("Instance Pack", "0.9", "me", "1.20.1")),
+"resourcepacks/nested/local.zip": "local pack",
+}
+for name, data := range files {
+path := filepath.Join(instanceDir, filepath.FromSlash(name))
+os.MkdirAll(filepath.Dir(path), 0o755)
+if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+t.Fatal(err)
+}
+}
+
+fingerprint := func(name string) int64 { return ComputeFingerprint([]byte(files[name])) }
+matches := map[int64]FingerprintMatch{
+fingerprint("mods/jei.jar"):               {ID: 238222, File: File{ID: 1, ModID: 238222, FileFingerprint: fingerprint("mods/jei.jar")}},
+fingerprint("mods/jei-old.jar"):           {ID: 238222, File: File{ID: 2, ModID: 238222, FileFingerprint: fingerprint("mods/jei-old.jar")}},
+fingerprint("mods/wrong-folder.zip"):      {ID: 3, File: File{ID: 3, ModID: 3, FileFingerprint: fingerprint("mods/wrong-folder.zip")}},
+fingerprint("resourcepacks/faithful.zip"): {ID: 4, File: File{ID: 4, ModID: 4, FileFingerprint: fingerprint("resourcepacks/faithful.zip")}},
+}
+mods := map[int]Mod{
+238222: {ID: 238222, Name: "Just Enough Items", ClassID: ClassIDMods, Authors: []Author{{Name: "mezz"}},
+Links: ModLinks{WebsiteURL: "https://www.curseforge.com/minecraft/mc-mods/jei"}},
+3: {ID: 3, Name: "Stray", ClassID: ClassIDResourcePacks},
+4: {ID: 4, Name: "Faithful <32x>", ClassID: ClassIDResourcePacks},
+}
+
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch r.URL.Path {
+case "/v1/fingerprints/432":
+var request FingerprintsMatchesRequest
+json.NewDecoder(r.Body).Decode(&request)
+var result FingerprintMatchesResult
+for _, fp := range request.Fingerprints {
+if match, ok := matches[fp]; ok {
+result.ExactMatches = append(result.ExactMatches, match)
+}
+}
+json.NewEncoder(w).Encode(Response[FingerprintMatchesResult]{Data: result})
+case "/v1/mods":
+var request GetModsByIDsRequest
+json.NewDecoder(r.Body).Decode(&request)
+var found []Mod
+for _, id := range request.ModIDs {
+found = append(found, mods[id])
+}
+json.NewEncoder(w).Encode(Response[[]Mod]{Data: found})
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+defer server.Close()
+
+fsys := NewMemFS()
+export, err := ExportModpack(NewServerWithURL("key", server.URL), instanceDir, "out/pack.zip", ModpackExportOptions{FS: fsys})
+if err != nil {
+t.Fatalf("ExportModpack failed: %v", err)
+}
+
+want := []ManifestFile{
+{ProjectID: 238222, FileID: 2, Required: true},
+{ProjectID: 4, FileID: 4, Required: true},
+}
+if !reflect.DeepEqual(export.Manifest.Files, want) {
+t.Errorf("Files = %+v, want %+v", export.Manifest.Files, want)
+}
+if export.Manifest.Name != "Instance Pack" || export.Manifest.Minecraft.Version != "1.20.1" {
+t.Errorf("manifest header not taken from the instance: %+v", export.Manifest)
+}
+
+wantOverrides := []string{
+"config/jei/jei-client.toml",
+"kubejs/server_scripts/main.js",
+"mods/jei.jar",
+"mods/local.jar",
+"mods/wrong-folder.zip",
+"resourcepacks/nested/local.zip",
+"shaderpacks/bsl.zip.txt",
+}
+if !reflect.DeepEqual(export.Overrides, wantOverrides) {
+t.Errorf("Overrides = %v, want %v", export.Overrides, wantOverrides)
+}
+
+data, err := fsys.ReadFile("out/pack.zip")
+if err != nil {
+t.Fatalf("modpack not written: %v", err)
+}
+archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+if err != nil {
+t.Fatalf("invalid zip: %v", err)
+}
+var names []string
+entries := make(map[string]*zip.File)
+for _, entry := range archive.File {
+names = append(names, entry.Name)
+entries[entry.Name] = entry
+}
+sort.Strings(names)
+wantNames := []string{"manifest.json", "modlist.html"}
+for _, override := range wantOverrides {
+wantNames = append(wantNames, "overrides/"+override)
+}
+sort.Strings(wantNames)
+if !reflect.DeepEqual(names, wantNames) {
+t.Errorf("zip entries = %v, want %v", names, wantNames)
+}
+
+manifest, err := ReadModpackManifest(fsys, "out/pack.zip")
+if err != nil {
+t.Fatalf("ReadModpackManifest failed: %v", err)
+}
+if err := manifest.Validate(); err != nil || len(manifest.Files) != 2 {
+t.Errorf("exported manifest = %+v, %v", manifest, err)
+}
+
+in, _ := entries["modlist.html"].Open()
+var modList bytes.Buffer
+modList.ReadFrom(in)
+in.Close()
+for _, expected := range []string{
+`<li><a href="https://www.curseforge.com/minecraft/mc-mods/jei">Just Enough Items (by mezz)</a></li>`,
+`<li><a href="https://www.curseforge.com/projects/4">Faithful &lt;32x&gt;</a></li>`,
+} {
+if !strings.Contains(modList.String(), expected) {
+t.Errorf("modlist.html missing %s:\n%s", expected, modList.String())
+}
+}
+
+if _, err := ExportModpack(NewServerWithURL("key", server.URL), t.TempDir(), "out/none.zip", ModpackExportOptions{FS: fsys}); err == nil {
+t.Error("expected error without a manifest")
+}
+}
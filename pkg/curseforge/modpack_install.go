@@ -0,0 +1,324 @@
+package curseforge
+
+import (
+"archive/zip"
+"errors"
+"fmt"
+"io"
+"io/fs"
+"os"
+"path"
+"strings"
+)
+
+// classFolders maps project classes to the instance folder their files go in
+var classFolders = map[int]string{
+ClassIDMods:          "mods",
+ClassIDResourcePacks: "resourcepacks",
+ClassIDShaders:       "shaderpacks",
+ClassIDWorlds:        "saves",
+}
+
+// ClassFolder returns the instance folder files of a project class are installed to
+// Unknown classes are installed to mods
+func ClassFolder(classID int) string {
+if folder, ok := classFolders[classID]; ok {
+return folder
+}
+return classFolders[ClassIDMods]
+}
+
+// ModpackInstallOptions configures InstallModpack
+type ModpackInstallOptions struct {
+// FS is the filesystem the modpack is read from and the instance written
+// to, defaults to OSFS
+FS WritableFS
+
+// IncludeOptional also installs files the manifest does not mark required
+IncludeOptional bool
+
+// DryRun plans the installation without writing anything
+DryRun bool
+
+// ServerOnly installs mods only, leaving out client-only mods (see
+// File.SupportsEnvironment) and resource pack, shader and world projects
+ServerOnly bool
+
+// Download configures the downloads; its Download.FS is replaced by FS
+Download DownloadManagerOptions
+}
+
+// ModpackInstallation is the result of InstallModpack
+type ModpackInstallation struct {
+Manifest    *ModpackManifest
+InstanceDir string
+
+Downloads        []DownloadRequest // files downloaded, or to download on a dry run
+AlreadyInstalled []DownloadRequest // files already present with matching hashes
+Overrides        []string          // override files extracted, relative to InstanceDir
+Skipped          []ManifestFile    // optional files left out
+Missing          []ManifestFile    // files CurseForge did not return, or with an unsafe file name
+ClientOnly       []File            // files left out by ServerOnly
+
+// ManualDownloads lists files whose author disallows third-party
+// distribution; see FindManualDownloads to install them
+ManualDownloads []*ManualDownloadRequired
+
+// Summary is the outcome of the downloads, nil on a dry run
+Summary *DownloadSummary
+}
+
+// Err returns the missing files and failed downloads joined into one error, or nil
+// Manual downloads are not errors, see Complete
+func (i *ModpackInstallation) Err() error {
+var errs []error
+for _, file := range i.Missing {
+errs = append(errs, fmt.Errorf("file %d of project %d not found or has an unsafe file name", file.FileID, file.ProjectID))
+}
+if i.Summary != nil {
+errs = append(errs, i.Summary.Err())
+}
+return errors.Join(errs...)
+}
+
+// Complete reports whether every file is installed
+func (i *ModpackInstallation) Complete() bool {
+return i.Summary != nil && i.Err() == nil && len(i.ManualDownloads) == 0
+}
+
+// InstallModpack installs a modpack zip into instanceDir
+//
+// The manifest is validated and its files resolved with a single GetFiles
+// request. Each file is downloaded with hash verification into the folder of
+// its project class (see ClassFolder) and the overrides are extracted on top.
+// Running it again resumes an interrupted installation: files already present
+// with matching hashes are skipped and partial downloads are continued.
+// Files that must be downloaded manually are reported, not downloaded.
+func InstallModpack(server CurseForgeServer, modpackPath string, instanceDir string, options ModpackInstallOptions) (*ModpackInstallation, error) {
+archive, closeArchive, err := openModpackZip(orOSFS(options.FS), modpackPath)
+if err != nil {
+return nil, err
+}
+defer closeArchive()
+
+return installModpackArchive(server, archive, instanceDir, options)
+}
+
+// InstallModpackFile downloads a file of a ClassIDModpacks project and installs it into instanceDir
+// The modpack zip is verified into a temporary file that is removed afterwards,
+// it is not written to the instance
+func InstallModpackFile(server CurseForgeServer, file File, instanceDir string, options ModpackInstallOptions) (*ModpackInstallation, error) {
+archive, closeArchive, err := downloadZipToTemp(file, options.Download.Download)
+if err != nil {
+return nil, err
+}
+defer closeArchive()
+
+return installModpackArchive(server, archive, instanceDir, options)
+}
+
+// downloadZipToTemp downloads a zip file into a temporary file of the OS
+// filesystem and opens it, so large packs are not held in memory
+// The returned function closes the archive and removes the temporary file
+func downloadZipToTemp(file File, options DownloadOptions) (*zip.Reader, func(), error) {
+tmp, err := os.CreateTemp("", "curseforge-*.zip")
+if err != nil {
+return nil, nil, fmt.Errorf("failed to create temporary file: %w", err)
+}
+cleanup := func() {
+tmp.Close()
+os.Remove(tmp.Name())
+}
+
+size, err := DownloadTo(tmp, file, options)
+if err != nil {
+cleanup()
+return nil, nil, fmt.Errorf("failed to download %s: %w", file.FileName, err)
+}
+archive, err := zip.NewReader(tmp, size)
+if err != nil {
+cleanup()
+return nil, nil, fmt.Errorf("failed to open %s: %w", file.FileName, err)
+}
+return archive, cleanup, nil
+}
+
+// installModpackArchive plans and, unless DryRun is set, performs the installation
+func installModpackArchive(server CurseForgeServer, archive *zip.Reader, instanceDir string, options ModpackInstallOptions) (*ModpackInstallation, error) {
+fsys := orOSFS(options.FS)
+
+manifest, err := ReadModpackManifestZip(archive)
+if err != nil {
+return nil, err
+}
+if err := manifest.Validate(); err != nil {
+return nil, err
+}
+
+installation := &ModpackInstallation{Manifest: manifest, InstanceDir: instanceDir}
+if err := planModpackFiles(server, fsys, installation, options); err != nil {
+return nil, err
+}
+
+overrides, err := modpackOverrides(archive, manifest.OverridesDir())
+if err != nil {
+return nil, err
+}
+for _, entry := range overrides {
+installation.Overrides = append(installation.Overrides, entry.relative)
+}
+
+contextLogger.Trace(fmt.Sprintf("Modpack %s: %d downloads, %d installed, %d overrides, %d manual downloads",
+manifest.Name, len(installation.Downloads), len(installation.AlreadyInstalled), len(overrides), len(installation.ManualDownloads)))
+if options.DryRun {
+return installation, nil
+}
+
+for _, entry := range overrides {
+if err := extractZipEntry(fsys, entry.file, joinPath(fsys, instanceDir, entry.relative)); err != nil {
+return installation, fmt.Errorf("failed to extract override %s: %w", entry.relative, err)
+}
+}
+
+downloadOptions := options.Download
+downloadOptions.Download.FS = fsys
+installation.Summary = DownloadFiles(installation.Downloads, downloadOptions)
+installation.ManualDownloads = append(installation.ManualDownloads, installation.Summary.ManualDownloads...)
+return installation, nil
+}
+
+// planModpackFiles resolves the manifest files and sorts them into the
+// downloads, already installed files and manual downloads of the installation
+func planModpackFiles(server CurseForgeServer, fsys WritableFS, installation *ModpackInstallation, options ModpackInstallOptions) error {
+var wanted []ManifestFile
+for _, entry := range installation.Manifest.Files {
+if entry.Required || options.IncludeOptional {
+wanted = append(wanted, entry)
+} else {
+installation.Skipped = append(installation.Skipped, entry)
+}
+}
+if len(wanted) == 0 {
+return nil
+}
+
+fileIDs := make([]int, 0, len(wanted))
+projectIDs := make([]int, 0, len(wanted))
+for _, entry := range wanted {
+fileIDs = append(fileIDs, entry.FileID)
+projectIDs = append(projectIDs, entry.ProjectID)
+}
+
+files, err := GetFiles(server, fileIDs)
+if err != nil {
+return fmt.Errorf("failed to get modpack files: %w", err)
+}
+mods, err := GetMods(server, projectIDs)
+if err != nil {
+return fmt.Errorf("failed to get modpack projects: %w", err)
+}
+
+filesByID := make(map[int]File, len(files))
+for _, file := range files {
+filesByID[file.ID] = file
+}
+modsByID := make(map[int]*Mod, len(mods))
+for i := range mods {
+modsByID[mods[i].ID] = &mods[i]
+}
+
+for _, entry := range wanted {
+file, ok := filesByID[entry.FileID]
+if !ok || !isSafeFileName(file.FileName) {
+contextLogger.Trace(fmt.Sprintf("Modpack file %d is missing or has an unsafe file name %q", entry.FileID, file.FileName))
+installation.Missing = append(installation.Missing, entry)
+continue
+}
+
+folder := ClassFolder(ClassIDMods)
+if mod := modsByID[entry.ProjectID]; mod != nil {
+folder = ClassFolder(mod.ClassID)
+}
+if options.ServerOnly && (folder != ClassFolder(ClassIDMods) || !file.SupportsEnvironment(EnvironmentServer)) {
+installation.ClientOnly = append(installation.ClientOnly, file)
+continue
+}
+request := DownloadRequest{
+File:        file,
+Destination: joinPath(fsys, installation.InstanceDir, folder, file.FileName),
+}
+
+switch {
+case isInstalled(fsys, request):
+installation.AlreadyInstalled = append(installation.AlreadyInstalled, request)
+case file.DownloadURL == "":
+installation.ManualDownloads = append(installation.ManualDownloads, NewManualDownloadRequired(file, modsByID[entry.ProjectID], request.Destination))
+default:
+installation.Downloads = append(installation.Downloads, request)
+}
+}
+return nil
+}
+
+// isSafeFileName reports whether name, taken from the API, is a single path
+// element that cannot leave the folder it is installed to on any OS
+func isSafeFileName(name string) bool {
+return fs.ValidPath(name) && name != "." && !strings.ContainsAny(name, `/\`)
+}
+
+// isInstalled reports whether the destination already holds the requested file
+func isInstalled(fsys WritableFS, request DownloadRequest) bool {
+in, err := OpenFS(fsys, request.Destination)
+if err != nil {
+return false
+}
+defer in.Close()
+
+hashes, err := ComputeHashesFromReadSeeker(in)
+if err != nil {
+return false
+}
+return VerifyHashes(request.File, hashes, false) == nil
+}
+
+// archiveFile is a file of a zip to extract
+type archiveFile struct {
+file     *zip.File
+relative string // slash separated path inside the destination
+}
+
+// modpackOverrides lists the files under the overrides directory
+func modpackOverrides(archive *zip.Reader, overridesDir string) ([]archiveFile, error) {
+return archiveFilesUnder(archive, path.Clean(overridesDir)+"/")
+}
+
+// archiveFilesUnder lists the files whose name starts with prefix, relative to it
+// Paths that would escape the destination are rejected
+func archiveFilesUnder(archive *zip.Reader, prefix string) ([]archiveFile, error) {
+var files []archiveFile
+for _, entry := range archive.File {
+relative, ok := strings.CutPrefix(entry.Name, prefix)
+if !ok || relative == "" || entry.FileInfo().IsDir() {
+continue
+}
+if !fs.ValidPath(relative) || strings.Contains(relative, "\\") {
+return nil, fmt.Errorf("unsafe path in modpack: %s", entry.Name)
+}
+files = append(files, archiveFile{file: entry, relative: relative})
+}
+return files, nil
+}
+
+// extractZipEntry writes a zip entry to destination atomically
+func extractZipEntry(fsys WritableFS, entry *zip.File, destination string) error {
+in, err := entry.Open()
+if err != nil {
+return err
+}
+defer in.Close()
+
+return writeFileFS(fsys, destination, 0o644, func(w io.Writer) error {
+_, err := io.Copy(w, in)
+return err
+})
+}
@@ -0,0 +1,299 @@
+package curseforge
+
+import (
+"encoding/json"
+"net/http"
+"net/http/httptest"
+"reflect"
+"strconv"
+"strings"
+"sync/atomic"
+"testing"
+)
+
+// modpackTestServer serves the CurseForge API and file downloads for a modpack
+// Set mods, files and content before making requests
+type modpackTestServer struct {
+*httptest.Server
+mods      []Mod
+files     []File
+content   map[string][]byte
+downloads atomic.Int32
+}
+
+func newModpackTestServer(t *testing.T) *modpackTestServer {
+s := &modpackTestServer{}
+s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch {
+case r.URL.Path == "/v1/mods/files":
+var request GetFilesRequest
+json.NewDecoder(r.Body).Decode(&request)
+var found []File
+for _, file := range s.files {
+for _, id := range request.FileIDs {
+if file.ID == id {
+found = append(found, file)
+}
+}
+}
+json.NewEncoder(w).Encode(Response[[]File]{Data: found})
+case r.URL.Path == "/v1/mods":
+json.NewEncoder(w).Encode(Response[[]Mod]{Data: s.mods})
+case strings.HasPrefix(r.URL.Path, "/v1/mods/"):
+// /v1/mods/{modId}/files and /v1/mods/{modId}/files/{fileId}
+parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/mods/"), "/")
+var found []File
+for _, file := range s.files {
+if strconv.Itoa(file.ModID) == parts[0] && (len(parts) < 3 || strconv.Itoa(file.ID) == parts[2]) {
+found = append(found, file)
+}
+}
+if len(parts) < 3 {
+json.NewEncoder(w).Encode(PaginatedResponse[[]File]{Data: found, Pagination: Pagination{ResultCount: len(found), TotalCount: len(found)}})
+} else if len(found) == 1 {
+json.NewEncoder(w).Encode(Response[File]{Data: found[0]})
+} else {
+w.WriteHeader(http.StatusNotFound)
+}
+case strings.HasPrefix(r.URL.Path, "/download/"):
+s.downloads.Add(1)
+data, ok := s.content[strings.TrimPrefix(r.URL.Path, "/download/")]
+if !ok {
+w.WriteHeader(http.StatusNotFound)
+return
+}
+w.Write(data)
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+t.Cleanup(s.Close)
+return s
+}
+
+// modpackTestFile describes downloadable content of a project
+func modpackTestFile(server *modpackTestServer, id int, modID int, name string, content []byte) File {
+file := testDownloadFile(server.URL+"/download/"+name, content)
+file.ID = id
+file.ModID = modID
+file.FileName = name
+return file
+}
+
+func TestInstallModpack(t *testing.T) {
+server := newModpackTestServer(t)
+server.content = map[string][]byte{
+"a.jar":    fingerprintTestData(5000),
+"pack.zip": fingerprintTestData(3000),
+}
+server.mods = []Mod{
+{ID: 1, Name: "A", ClassID: ClassIDMods},
+{ID: 2, Name: "Pack", ClassID: ClassIDResourcePacks},
+{ID: 3, Name: "Manual", ClassID: ClassIDMods},
+}
+server.files = []File{
+modpackTestFile(server, 10, 1, "a.jar", server.content["a.jar"]),
+modpackTestFile(server, 20, 2, "pack.zip", server.content["pack.zip"]),
+{ID: 30, ModID: 3, FileName: "manual.jar", FileLength: 10},
+}
+cf := NewServerWithURL("key", server.URL)
+
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+manifest.SetModLoader(ModLoaderForge, "47.2.0")
+manifest.AddFile(1, 10, true)
+manifest.AddFile(2, 20, true)
+manifest.AddFile(3, 30, true)
+manifest.AddFile(4, 40, false)
+manifest.AddFile(5, 50, true)
+manifestJSON, err := manifest.Encode()
+if err != nil {
+t.Fatal(err)
+}
+
+fsys := NewMemFS()
+if err := fsys.WriteFile("pack.zip", modpackTestZip(t, map[string]string{
+"manifest.json":              string(manifestJSON),
+"overrides/config/a.cfg":     "a=1",
+"overrides/options.txt":      "fov:90",
+"overrides/config/":          "",
+"modlist.html":               "<ul></ul>",
+"client-overrides/ignored.x": "",
+}), 0o644); err != nil {
+t.Fatal(err)
+}
+
+options := ModpackInstallOptions{FS: fsys, DryRun: true, Download: DownloadManagerOptions{Workers: 2}}
+plan, err := InstallModpack(cf, "pack.zip", "instance", options)
+if err != nil {
+t.Fatalf("InstallModpack dry run failed: %v", err)
+}
+if !reflect.DeepEqual(fsys.Files(), []string{"pack.zip"}) {
+t.Errorf("dry run wrote files: %v", fsys.Files())
+}
+if plan.Summary != nil || plan.Complete() {
+t.Error("dry run should not download")
+}
+destinations := []string{}
+for _, request := range plan.Downloads {
+destinations = append(destinations, request.Destination)
+}
+if !reflect.DeepEqual(destinations, []string{"instance/mods/a.jar", "instance/resourcepacks/pack.zip"}) {
+t.Errorf("Downloads = %v", destinations)
+}
+if !reflect.DeepEqual(plan.Overrides, []string{"config/a.cfg", "options.txt"}) {
+t.Errorf("Overrides = %v", plan.Overrides)
+}
+if len(plan.ManualDownloads) != 1 || plan.ManualDownloads[0].FileID != 30 || plan.ManualDownloads[0].Destination != "instance/mods/manual.jar" {
+t.Errorf("ManualDownloads = %+v", plan.ManualDownloads)
+}
+if len(plan.Skipped) != 1 || plan.Skipped[0].FileID != 40 {
+t.Errorf("Skipped = %+v", plan.Skipped)
+}
+if len(plan.Missing) != 1 || plan.Missing[0].FileID != 50 {
+t.Errorf("Missing = %+v", plan.Missing)
+}
+
+options.DryRun = false
+installation, err := InstallModpack(cf, "pack.zip", "instance", options)
+if err != nil {
+t.Fatalf("InstallModpack failed: %v", err)
+}
+if len(installation.Summary.Succeeded) != 2 || installation.Summary.Err() != nil {
+t.Errorf("Summary = %+v", installation.Summary)
+}
+if err := installation.Err(); err == nil || !strings.Contains(err.Error(), "file 50") {
+t.Errorf("Err = %v, want missing file 50", err)
+}
+if installation.Complete() {
+t.Error("installation with missing and manual files should not be complete")
+}
+want := []string{"instance/config/a.cfg", "instance/mods/a.jar", "instance/options.txt", "instance/resourcepacks/pack.zip", "pack.zip"}
+if !reflect.DeepEqual(fsys.Files(), want) {
+t.Errorf("Files = %v, want %v", fsys.Files(), want)
+}
+if data, _ := fsys.ReadFile("instance/config/a.cfg"); string(data) != "a=1" {
+t.Errorf("override content = %q", data)
+}
+if downloads := server.downloads.Load(); downloads != 2 {
+t.Errorf("downloads = %d, want 2", downloads)
+}
+
+resumed, err := InstallModpack(cf, "pack.zip", "instance", options)
+if err != nil {
+t.Fatalf("InstallModpack resume failed: %v", err)
+}
+if len(resumed.AlreadyInstalled) != 2 || len(resumed.Downloads) != 0 {
+t.Errorf("resume should skip installed files: installed %d, downloads %d", len(resumed.AlreadyInstalled), len(resumed.Downloads))
+}
+if downloads := server.downloads.Load(); downloads != 2 {
+t.Errorf("resume downloaded again: %d downloads", downloads)
+}
+
+withOptional, err := InstallModpack(cf, "pack.zip", "instance", ModpackInstallOptions{FS: fsys, DryRun: true, IncludeOptional: true})
+if err != nil {
+t.Fatalf("InstallModpack failed: %v", err)
+}
+if len(withOptional.Skipped) != 0 || len(withOptional.Missing) != 2 {
+t.Errorf("optional file 40 should be requested: skipped %v, missing %v", withOptional.Skipped, withOptional.Missing)
+}
+}
+
+func TestInstallModpackFile(t *testing.T) {
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+zipData := modpackTestZip(t, map[string]string{
+"manifest.json":         mustEncodeManifest(t, manifest),
+"overrides/options.txt": "fov:90",
+})
+server := newModpackTestServer(t)
+server.content = map[string][]byte{"pack.zip": zipData}
+cf := NewServerWithURL("key", server.URL)
+
+packFile := testDownloadFile(server.URL+"/download/pack.zip", zipData)
+fsys := NewMemFS()
+installation, err := InstallModpackFile(cf, packFile, "instance", ModpackInstallOptions{FS: fsys})
+if err != nil {
+t.Fatalf("InstallModpackFile failed: %v", err)
+}
+if !installation.Complete() {
+t.Errorf("installation should be complete: %v", installation.Err())
+}
+if !reflect.DeepEqual(fsys.Files(), []string{"instance/options.txt"}) {
+t.Errorf("Files = %v", fsys.Files())
+}
+
+packFile.Hashes[0].Value = strings.Repeat("0", 40)
+if _, err := InstallModpackFile(cf, packFile, "instance", ModpackInstallOptions{FS: fsys}); !IsHashMismatch(err) {
+t.Errorf("expected hash mismatch, got %v", err)
+}
+}
+
+func TestInstallModpackRejectsUnsafeOverrides(t *testing.T) {
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+fsys := NewMemFS()
+fsys.WriteFile("pack.zip", modpackTestZip(t, map[string]string{
+"manifest.json":      mustEncodeManifest(t, manifest),
+"overrides/../evil":  "x",
+"overrides/good.txt": "y",
+}), 0o644)
+
+_, err := InstallModpack(NewServerWithURL("key", "http://127.0.0.1:0"), "pack.zip", "instance", ModpackInstallOptions{FS: fsys})
+if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+t.Errorf("expected unsafe path error, got %v", err)
+}
+if !reflect.DeepEqual(fsys.Files(), []string{"pack.zip"}) {
+t.Errorf("nothing should be written: %v", fsys.Files())
+}
+}
+
+func TestInstallModpackRejectsUnsafeFileNames(t *testing.T) {
+server := newModpackTestServer(t)
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+for i, name := range []string{"good.jar", "..", ".", `..\evil.jar`, "../evil.jar", ""} {
+file := modpackTestFile(server, 100+i, 10+i, "good.jar", []byte("x"))
+file.FileName = name
+server.files = append(server.files, file)
+manifest.AddFile(file.ModID, file.ID, true)
+}
+fsys := NewMemFS()
+fsys.WriteFile("pack.zip", modpackTestZip(t, map[string]string{"manifest.json": mustEncodeManifest(t, manifest)}), 0o644)
+
+installation, err := InstallModpack(NewServerWithURL("key", server.URL), "pack.zip", "instance", ModpackInstallOptions{FS: fsys, DryRun: true})
+if err != nil {
+t.Fatalf("InstallModpack failed: %v", err)
+}
+if len(installation.Downloads) != 1 || installation.Downloads[0].Destination != "instance/mods/good.jar" {
+t.Errorf("Downloads = %+v, want only good.jar", installation.Downloads)
+}
+var missing []int
+for _, entry := range installation.Missing {
+missing = append(missing, entry.FileID)
+}
+if !reflect.DeepEqual(missing, []int{101, 102, 103, 104, 105}) {
+t.Errorf("Missing = %v, want every unsafe file name", missing)
+}
+}
+
+func TestClassFolder(t *testing.T) {
+tests := map[int]string{
+ClassIDMods:          "mods",
+ClassIDResourcePacks: "resourcepacks",
+ClassIDShaders:       "shaderpacks",
+ClassIDWorlds:        "saves",
+0:                    "mods",
+}
+for classID, want := range tests {
+if got := ClassFolder(classID); got != want {
+t.Errorf("ClassFolder(%d) = %s, want %s", classID, got, want)
+}
+}
+}
+
+func mustEncodeManifest(t *testing.T, manifest *ModpackManifest) string {
+t.Helper()
+data, err := manifest.Encode()
+if err != nil {
+t.Fatal(err)
+}
+return string(data)
+}
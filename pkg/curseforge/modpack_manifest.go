@@ -0,0 +1,348 @@
+package curseforge
+
+import (
+"archive/zip"
+"bytes"
+"encoding/json"
+"errors"
+"fmt"
+"io"
+"path"
+"strings"
+)
+
+// CurseForge modpack format
+// A modpack is a zip (or an extracted directory) holding manifest.json, which
+// lists the CurseForge files to install, and an overrides directory copied on
+// top of the instance as is (configs, scripts, resource packs...).
+
+const (
+// ModpackManifestFileName is the name of the manifest at the root of a modpack
+ModpackManifestFileName = "manifest.json"
+// ModpackManifestType is the manifestType of Minecraft modpacks
+ModpackManifestType = "minecraftModpack"
+// ModpackManifestVersion is the manifestVersion this package reads and writes
+ModpackManifestVersion = 1
+// DefaultOverridesDir is the overrides directory used when the manifest names none
+DefaultOverridesDir = "overrides"
+)
+
+// ErrInvalidManifest is returned by ModpackManifest.Validate, wrapped with the problems found
+var ErrInvalidManifest = errors.New("invalid modpack manifest")
+
+// ModpackManifest represents the manifest.json of a CurseForge modpack
+type ModpackManifest struct {
+Minecraft       ManifestMinecraft `json:"minecraft"`
+ManifestType    string            `json:"manifestType"`
+ManifestVersion int               `json:"manifestVersion"`
+Name            string            `json:"name"`
+Version         string            `json:"version"`
+Author          string            `json:"author"`
+Files           []ManifestFile    `json:"files"`
+Overrides       string            `json:"overrides"`
+}
+
+// ManifestMinecraft is the game section of a modpack manifest
+type ManifestMinecraft struct {
+Version        string              `json:"version"`
+ModLoaders     []ManifestModLoader `json:"modLoaders"`
+RecommendedRAM int                 `json:"recommendedRam,omitempty"` // in MB
+}
+
+// ManifestModLoader is a mod loader of a modpack
+// ID is the loader name and version, e.g. "forge-47.2.0" or "fabric-0.15.3"
+type ManifestModLoader struct {
+ID      string `json:"id"`
+Primary bool   `json:"primary"`
+}
+
+// ManifestFile is a CurseForge file installed by a modpack
+type ManifestFile struct {
+ProjectID int  `json:"projectID"`
+FileID    int  `json:"fileID"`
+Required  bool `json:"required"`
+}
+
+// UnmarshalJSON treats files without a required flag as required
+func (f *ManifestFile) UnmarshalJSON(data []byte) error {
+type plain ManifestFile
+file := plain{Required: true}
+if err := json.Unmarshal(data, &file); err != nil {
+return err
+}
+*f = ManifestFile(file)
+return nil
+}
+
+// NewManifestModLoader builds the manifest entry of a loader version
+func NewManifestModLoader(loader ModLoaderType, version string, primary bool) ManifestModLoader {
+return ManifestModLoader{
+ID:      strings.ToLower(loader.String()) + "-" + version,
+Primary: primary,
+}
+}
+
+// Type returns the loader named by the ID, or ModLoaderAny if it is unknown
+func (l ManifestModLoader) Type() ModLoaderType {
+name, _, _ := strings.Cut(l.ID, "-")
+return ModLoaderFromString(name)
+}
+
+// LoaderVersion returns the version part of the ID
+func (l ManifestModLoader) LoaderVersion() string {
+_, version, _ := strings.Cut(l.ID, "-")
+return version
+}
+
+// NewModpackManifest creates an empty manifest for a Minecraft version
+func NewModpackManifest(name string, version string, author string, minecraftVersion string) *ModpackManifest {
+return &ModpackManifest{
+Minecraft: ManifestMinecraft{
+Version:    minecraftVersion,
+ModLoaders: []ManifestModLoader{},
+},
+ManifestType:    ModpackManifestType,
+ManifestVersion: ModpackManifestVersion,
+Name:            name,
+Version:         version,
+Author:          author,
+Files:           []ManifestFile{},
+Overrides:       DefaultOverridesDir,
+}
+}
+
+// PrimaryModLoader returns the loader flagged primary
+// A manifest with a single loader that is not flagged still returns it
+func (m *ModpackManifest) PrimaryModLoader() (ManifestModLoader, bool) {
+for _, loader := range m.Minecraft.ModLoaders {
+if loader.Primary {
+return loader, true
+}
+}
+if len(m.Minecraft.ModLoaders) == 1 {
+return m.Minecraft.ModLoaders[0], true
+}
+return ManifestModLoader{}, false
+}
+
+// SetModLoader makes loader the primary mod loader, replacing any other loader of the same type
+func (m *ModpackManifest) SetModLoader(loader ModLoaderType, version string) {
+loaders := []ManifestModLoader{NewManifestModLoader(loader, version, true)}
+for _, existing := range m.Minecraft.ModLoaders {
+if existing.Type() == loader {
+continue
+}
+existing.Primary = false
+loaders = append(loaders, existing)
+}
+m.Minecraft.ModLoaders = loaders
+}
+
+// AddFile adds a file to the manifest, replacing the entry of the same project
+func (m *ModpackManifest) AddFile(projectID int, fileID int, required bool) {
+entry := ManifestFile{ProjectID: projectID, FileID: fileID, Required: required}
+for i, file := range m.Files {
+if file.ProjectID == projectID {
+m.Files[i] = entry
+return
+}
+}
+m.Files = append(m.Files, entry)
+}
+
+// FileIDs returns the file IDs listed in the manifest, in order
+func (m *ModpackManifest) FileIDs() []int {
+ids := make([]int, 0, len(m.Files))
+for _, file := range m.Files {
+ids = append(ids, file.FileID)
+}
+return ids
+}
+
+// OverridesDir returns the overrides directory, defaulting to DefaultOverridesDir
+func (m *ModpackManifest) OverridesDir() string {
+if m.Overrides == "" {
+return DefaultOverridesDir
+}
+return m.Overrides
+}
+
+// Validate checks the manifest can be installed
+// The error wraps ErrInvalidManifest and lists every problem found
+func (m *ModpackManifest) Validate() error {
+var problems []string
+problemf := func(format string, args ...interface{}) {
+problems = append(problems, fmt.Sprintf(format, args...))
+}
+
+if m.ManifestType != ModpackManifestType {
+problemf("manifestType is %q, expected %q", m.ManifestType, ModpackManifestType)
+}
+if m.ManifestVersion != ModpackManifestVersion {
+problemf("unsupported manifestVersion %d", m.ManifestVersion)
+}
+if strings.TrimSpace(m.Name) == "" {
+problemf("name is empty")
+}
+if strings.TrimSpace(m.Minecraft.Version) == "" {
+problemf("minecraft.version is empty")
+}
+
+primaries := 0
+for i, loader := range m.Minecraft.ModLoaders {
+if loader.Type() == ModLoaderAny {
+problemf("modLoaders[%d]: unknown mod loader %q", i, loader.ID)
+} else if loader.LoaderVersion() == "" {
+problemf("modLoaders[%d]: %q has no version", i, loader.ID)
+}
+if loader.Primary {
+primaries++
+}
+}
+if len(m.Minecraft.ModLoaders) > 1 && primaries != 1 {
+problemf("%d mod loaders flagged primary, expected 1", primaries)
+}
+
+projects := make(map[int]int)
+for i, file := range m.Files {
+if file.ProjectID <= 0 || file.FileID <= 0 {
+problemf("files[%d]: invalid projectID %d or fileID %d", i, file.ProjectID, file.FileID)
+continue
+}
+if previous, ok := projects[file.ProjectID]; ok {
+problemf("files[%d]: project %d already listed at files[%d]", i, file.ProjectID, previous)
+continue
+}
+projects[file.ProjectID] = i
+}
+
+overrides := path.Clean(m.OverridesDir())
+if path.IsAbs(overrides) || strings.Contains(overrides, "\\") || overrides == ".." || strings.HasPrefix(overrides, "../") {
+problemf("overrides %q must be a relative path inside the modpack", overrides)
+}
+
+if len(problems) > 0 {
+return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, "; "))
+}
+return nil
+}
+
+// Encode serializes the manifest as indented JSON
+func (m *ModpackManifest) Encode() ([]byte, error) {
+manifest := *m
+if manifest.Files == nil {
+manifest.Files = []ManifestFile{}
+}
+if manifest.Minecraft.ModLoaders == nil {
+manifest.Minecraft.ModLoaders = []ManifestModLoader{}
+}
+data, err := json.MarshalIndent(manifest, "", "  ")
+if err != nil {
+return nil, fmt.Errorf("failed to encode manifest: %w", err)
+}
+return append(data, '\n'), nil
+}
+
+// ParseModpackManifest decodes a manifest.json document
+func ParseModpackManifest(data []byte) (*ModpackManifest, error) {
+var manifest ModpackManifest
+if err := json.Unmarshal(data, &manifest); err != nil {
+return nil, fmt.Errorf("failed to parse manifest: %w", err)
+}
+return &manifest, nil
+}
+
+// ReadModpackManifest reads the manifest of a modpack zip or extracted modpack directory
+// fsys may be nil to read from the OS filesystem
+func ReadModpackManifest(fsys WritableFS, name string) (*ModpackManifest, error) {
+fsys = orOSFS(fsys)
+info, err := fsys.Stat(name)
+if err != nil {
+return nil, fmt.Errorf("failed to stat modpack: %w", err)
+}
+
+if info.IsDir() {
+data, err := ReadFileFS(fsys, joinPath(fsys, name, ModpackManifestFileName))
+if err != nil {
+return nil, fmt.Errorf("failed to read manifest: %w", err)
+}
+return ParseModpackManifest(data)
+}
+
+archive, closeArchive, err := openModpackZip(fsys, name)
+if err != nil {
+return nil, err
+}
+defer closeArchive()
+return ReadModpackManifestZip(archive)
+}
+
+// ReadModpackManifestZip reads the manifest at the root of an open modpack zip
+func ReadModpackManifestZip(archive *zip.Reader) (*ModpackManifest, error) {
+for _, entry := range archive.File {
+if path.Clean(entry.Name) != ModpackManifestFileName {
+continue
+}
+in, err := entry.Open()
+if err != nil {
+return nil, fmt.Errorf("failed to open manifest: %w", err)
+}
+defer in.Close()
+
+data, err := io.ReadAll(in)
+if err != nil {
+return nil, fmt.Errorf("failed to read manifest: %w", err)
+}
+return ParseModpackManifest(data)
+}
+return nil, fmt.Errorf("failed to read manifest: %s not found in modpack", ModpackManifestFileName)
+}
+
+// WriteModpackManifest validates the manifest and writes it to name
+func WriteModpackManifest(fsys WritableFS, name string, manifest *ModpackManifest) error {
+if err := manifest.Validate(); err != nil {
+return err
+}
+data, err := manifest.Encode()
+if err != nil {
+return err
+}
+if err := WriteFileFS(fsys, name, data, 0o644); err != nil {
+return fmt.Errorf("failed to write manifest: %w", err)
+}
+return nil
+}
+
+// openModpackZip opens a zip of a WritableFS
+// Files that cannot be read at random offsets (MemFS) are loaded into memory
+func openModpackZip(fsys WritableFS, name string) (*zip.Reader, func() error, error) {
+file, err := OpenFS(fsys, name)
+if err != nil {
+return nil, nil, fmt.Errorf("failed to open modpack: %w", err)
+}
+
+if readerAt, ok := file.(io.ReaderAt); ok {
+info, err := file.Stat()
+if err != nil {
+file.Close()
+return nil, nil, fmt.Errorf("failed to stat modpack: %w", err)
+}
+archive, err := zip.NewReader(readerAt, info.Size())
+if err != nil {
+file.Close()
+return nil, nil, fmt.Errorf("failed to open modpack zip: %w", err)
+}
+return archive, file.Close, nil
+}
+
+data, err := io.ReadAll(file)
+file.Close()
+if err != nil {
+return nil, nil, fmt.Errorf("failed to read modpack: %w", err)
+}
+archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+if err != nil {
+return nil, nil, fmt.Errorf("failed to open modpack zip: %w", err)
+}
+return archive, func() error { return nil }, nil
+}
@@ -0,0 +1,241 @@
+package curseforge
+
+import (
+"archive/zip"
+"bytes"
+"errors"
+"reflect"
+"sort"
+"strings"
+"testing"
+)
+
+const testModpackManifest = `{
+"minecraft": {
+"version": "1.20.1",
+"modLoaders": [
+{"id": "forge-47.2.0", "primary": true}
+],
+"recommendedRam": 6144
+},
+"manifestType": "minecraftModpack",
+"manifestVersion": 1,
+"name": "Test Pack",
+"version": "1.0.0",
+"author": "tester",
+"files": [
+{"projectID": 238222, "fileID": 4712866, "required": true},
+{"projectID": 32274, "fileID": 4608495, "required": false},
+{"projectID": 60089, "fileID": 4652215}
+],
+"overrides": "overrides"
+}`
+
+// modpackTestZip builds a zip archive holding the given files
+func modpackTestZip(t *testing.T, files map[string]string) []byte {
+t.Helper()
+names := make([]string, 0, len(files))
+for name := range files {
+names = append(names, name)
+}
+sort.Strings(names)
+
+var buf bytes.Buffer
+archive := zip.NewWriter(&buf)
+for _, name := range names {
+w, err := archive.Create(name)
+if err != nil {
+t.Fatalf("failed to create %s: %v", name, err)
+}
+if _, err := w.Write([]byte(files[name])); err != nil {
+t.Fatalf("failed to write %s: %v", name, err)
+}
+}
+if err := archive.Close(); err != nil {
+t.Fatalf("failed to close zip: %v", err)
+}
+return buf.Bytes()
+}
+
+func TestParseModpackManifest(t *testing.T) {
+manifest, err := ParseModpackManifest([]byte(testModpackManifest))
+if err != nil {
+t.Fatalf("ParseModpackManifest failed: %v", err)
+}
+if err := manifest.Validate(); err != nil {
+t.Errorf("Validate failed: %v", err)
+}
+
+if manifest.Name != "Test Pack" || manifest.Author != "tester" || manifest.Minecraft.Version != "1.20.1" {
+t.Errorf("unexpected header: %+v", manifest)
+}
+if manifest.Minecraft.RecommendedRAM != 6144 {
+t.Errorf("RecommendedRAM = %d, want 6144", manifest.Minecraft.RecommendedRAM)
+}
+
+loader, ok := manifest.PrimaryModLoader()
+if !ok || loader.Type() != ModLoaderForge || loader.LoaderVersion() != "47.2.0" {
+t.Errorf("PrimaryModLoader = %+v, %v", loader, ok)
+}
+
+want := []ManifestFile{
+{ProjectID: 238222, FileID: 4712866, Required: true},
+{ProjectID: 32274, FileID: 4608495, Required: false},
+{ProjectID: 60089, FileID: 4652215, Required: true},
+}
+if !reflect.DeepEqual(manifest.Files, want) {
+t.Errorf("Files = %+v, want %+v", manifest.Files, want)
+}
+if ids := manifest.FileIDs(); !reflect.DeepEqual(ids, []int{4712866, 4608495, 4652215}) {
+t.Errorf("FileIDs = %v", ids)
+}
+}
+
+func TestModpackManifestEncodeRoundTrip(t *testing.T) {
+manifest := NewModpackManifest("Round Trip", "2.0", "me", "1.20.4")
+manifest.SetModLoader(ModLoaderNeoForge, "20.4.80-beta")
+manifest.AddFile(1, 10, true)
+manifest.AddFile(2, 20, false)
+manifest.AddFile(1, 11, true)
+
+data, err := manifest.Encode()
+if err != nil {
+t.Fatalf("Encode failed: %v", err)
+}
+for _, expected := range []string{`"id": "neoforge-20.4.80-beta"`, `"manifestType": "minecraftModpack"`, `"overrides": "overrides"`} {
+if !strings.Contains(string(data), expected) {
+t.Errorf("encoded manifest missing %s:\n%s", expected, data)
+}
+}
+
+decoded, err := ParseModpackManifest(data)
+if err != nil {
+t.Fatalf("ParseModpackManifest failed: %v", err)
+}
+if !reflect.DeepEqual(decoded, manifest) {
+t.Errorf("round trip mismatch:\n got %+v\nwant %+v", decoded, manifest)
+}
+if len(decoded.Files) != 2 || decoded.Files[0].FileID != 11 {
+t.Errorf("AddFile should replace the entry of the same project: %+v", decoded.Files)
+}
+
+empty, err := (&ModpackManifest{}).Encode()
+if err != nil {
+t.Fatalf("Encode failed: %v", err)
+}
+if !strings.Contains(string(empty), `"files": []`) || !strings.Contains(string(empty), `"modLoaders": []`) {
+t.Errorf("empty lists should encode as arrays:\n%s", empty)
+}
+}
+
+func TestModpackManifestValidate(t *testing.T) {
+valid := func() *ModpackManifest {
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+manifest.SetModLoader(ModLoaderFabric, "0.15.3")
+manifest.AddFile(1, 10, true)
+return manifest
+}
+
+tests := []struct {
+name    string
+modify  func(m *ModpackManifest)
+problem string
+}{
+{"valid", func(m *ModpackManifest) {}, ""},
+{"wrong type", func(m *ModpackManifest) { m.ManifestType = "other" }, "manifestType"},
+{"wrong version", func(m *ModpackManifest) { m.ManifestVersion = 2 }, "manifestVersion 2"},
+{"no name", func(m *ModpackManifest) { m.Name = " " }, "name is empty"},
+{"no minecraft version", func(m *ModpackManifest) { m.Minecraft.Version = "" }, "minecraft.version"},
+{"unknown loader", func(m *ModpackManifest) { m.Minecraft.ModLoaders[0].ID = "rift-1.0" }, "unknown mod loader"},
+{"loader without version", func(m *ModpackManifest) { m.Minecraft.ModLoaders[0].ID = "fabric" }, "has no version"},
+{"two primaries", func(m *ModpackManifest) {
+m.Minecraft.ModLoaders = append(m.Minecraft.ModLoaders, NewManifestModLoader(ModLoaderQuilt, "0.23.0", true))
+}, "2 mod loaders flagged primary"},
+{"invalid file", func(m *ModpackManifest) { m.AddFile(2, 0, true) }, "files[1]: invalid"},
+{"duplicate project", func(m *ModpackManifest) {
+m.Files = append(m.Files, ManifestFile{ProjectID: 1, FileID: 12})
+}, "already listed"},
+{"escaping overrides", func(m *ModpackManifest) { m.Overrides = "../outside" }, "overrides"},
+{"absolute overrides", func(m *ModpackManifest) { m.Overrides = "/etc" }, "overrides"},
+}
+
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+manifest := valid()
+tt.modify(manifest)
+err := manifest.Validate()
+if tt.problem == "" {
+if err != nil {
+t.Errorf("Validate failed: %v", err)
+}
+return
+}
+if !errors.Is(err, ErrInvalidManifest) {
+t.Fatalf("Validate = %v, want ErrInvalidManifest", err)
+}
+if !strings.Contains(err.Error(), tt.problem) {
+t.Errorf("Validate = %v, want it to mention %q", err, tt.problem)
+}
+})
+}
+}
+
+func TestReadModpackManifest(t *testing.T) {
+fsys := NewMemFS()
+if err := fsys.WriteFile("packs/test.zip", modpackTestZip(t, map[string]string{
+"manifest.json":          testModpackManifest,
+"modlist.html":           "<ul></ul>",
+"overrides/config/a.cfg": "a=1",
+}), 0o644); err != nil {
+t.Fatal(err)
+}
+if err := fsys.WriteFile("extracted/manifest.json", []byte(testModpackManifest), 0o644); err != nil {
+t.Fatal(err)
+}
+if err := fsys.WriteFile("broken.zip", modpackTestZip(t, map[string]string{"readme.txt": "no manifest"}), 0o644); err != nil {
+t.Fatal(err)
+}
+
+for _, name := range []string{"packs/test.zip", "extracted"} {
+manifest, err := ReadModpackManifest(fsys, name)
+if err != nil {
+t.Fatalf("ReadModpackManifest(%s) failed: %v", name, err)
+}
+if manifest.Name != "Test Pack" || len(manifest.Files) != 3 {
+t.Errorf("ReadModpackManifest(%s) = %+v", name, manifest)
+}
+}
+
+if _, err := ReadModpackManifest(fsys, "broken.zip"); err == nil || !strings.Contains(err.Error(), "not found") {
+t.Errorf("expected missing manifest error, got %v", err)
+}
+if _, err := ReadModpackManifest(fsys, "missing.zip"); err == nil {
+t.Error("expected error for missing modpack")
+}
+}
+
+func TestWriteModpackManifest(t *testing.T) {
+fsys := NewMemFS()
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+manifest.SetModLoader(ModLoaderForge, "47.2.0")
+manifest.AddFile(1, 10, true)
+
+if err := WriteModpackManifest(fsys, "out/manifest.json", manifest); err != nil {
+t.Fatalf("WriteModpackManifest failed: %v", err)
+}
+read, err := ReadModpackManifest(fsys, "out")
+if err != nil {
+t.Fatalf("ReadModpackManifest failed: %v", err)
+}
+if !reflect.DeepEqual(read, manifest) {
+t.Errorf("read back %+v, want %+v", read, manifest)
+}
+
+manifest.Name = ""
+if err := WriteModpackManifest(fsys, "invalid/manifest.json", manifest); !errors.Is(err, ErrInvalidManifest) {
+t.Errorf("expected ErrInvalidManifest, got %v", err)
+}
+if _, err := fsys.Stat("invalid/manifest.json"); err == nil {
+t.Error("invalid manifest should not be written")
+}
+}
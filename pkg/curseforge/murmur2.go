@@ -1,6 +1,7 @@
 package curseforge
 
 import (
+"errors"
 "io"
 "os"
 )
@@ -14,8 +15,13 @@ murmur2M    uint32 = 0x5bd1e995
 murmur2R    int    = 24
 )
 
+// ErrFingerprintLengthMismatch is returned when the number of bytes hashed does
+// not match the length declared up front, e.g. because a file changed between passes
+var ErrFingerprintLengthMismatch = errors.New("fingerprint input length does not match declared length")
+
 // ComputeFileFingerprint computes the CurseForge fingerprint (Murmur2 hash) for a file
 // This is used for matching files against the CurseForge fingerprint database
+// The file is streamed twice with constant memory regardless of its size
 func ComputeFileFingerprint(filePath string) (int64, error) {
 file, err := os.Open(filePath)
 if err != nil {
@@ -23,17 +29,60 @@ return 0, err
 }
 defer file.Close()
 
-return ComputeFingerprintFromReader(file)
+return ComputeFingerprintFromReadSeeker(file)
 }
 
 // ComputeFingerprintFromReader computes the CurseForge fingerprint from an io.Reader
+// Readers that also implement io.Seeker are streamed with constant memory; other
+// readers are buffered because Murmur2 needs the filtered length before hashing
 func ComputeFingerprintFromReader(reader io.Reader) (int64, error) {
-data, err := io.ReadAll(reader)
+if seeker, ok := reader.(io.ReadSeeker); ok {
+return ComputeFingerprintFromReadSeeker(seeker)
+}
+
+// Only keep the bytes that are hashed instead of a full copy plus a filtered copy
+filtered := &whitespaceFilter{}
+if _, err := io.Copy(filtered, reader); err != nil {
+return 0, err
+}
+
+return int64(computeMurmur2(filtered.data)), nil
+}
+
+// ComputeFingerprintFromReadSeeker computes the CurseForge fingerprint in two
+// streaming passes: the first counts the non-whitespace bytes, the second hashes them
+// Reading starts at the current offset, matching ComputeFingerprintFromReader
+func ComputeFingerprintFromReadSeeker(reader io.ReadSeeker) (int64, error) {
+start, err := reader.Seek(0, io.SeekCurrent)
+if err != nil {
+return 0, err
+}
+
+length, err := CountFingerprintBytes(reader)
 if err != nil {
 return 0, err
 }
 
-return ComputeFingerprint(data), nil
+if _, err := reader.Seek(start, io.SeekStart); err != nil {
+return 0, err
+}
+
+hasher := NewMurmur2Hasher(length)
+if _, err := io.Copy(hasher, reader); err != nil {
+return 0, err
+}
+
+return hasher.Fingerprint()
+}
+
+// CountFingerprintBytes returns the number of bytes that take part in the
+// fingerprint, i.e. the length of the input once whitespace is filtered out
+func CountFingerprintBytes(reader io.Reader) (int64, error) {
+counter := &whitespaceCounter{}
+if _, err := io.Copy(counter, reader); err != nil {
+return 0, err
+}
+return counter.count, nil
 }
 
 // ComputeFingerprint computes the CurseForge fingerprint from byte data
@@ -51,13 +100,130 @@ return int64(hash)
 func filterWhitespace(data []byte) []byte {
 result := make([]byte, 0, len(data))
 for _, b := range data {
-if b != 9 && b != 10 && b != 13 && b != 32 {
+if !isFingerprintWhitespace(b) {
 result = append(result, b)
 }
 }
 return result
 }
 
+// isFingerprintWhitespace reports whether a byte is skipped by the fingerprint
+func isFingerprintWhitespace(b byte) bool {
+return b == 9 || b == 10 || b == 13 || b == 32
+}
+
+// whitespaceCounter is an io.Writer counting the bytes that are not filtered out
+type whitespaceCounter struct {
+count int64
+}
+
+func (c *whitespaceCounter) Write(p []byte) (int, error) {
+for _, b := range p {
+if !isFingerprintWhitespace(b) {
+c.count++
+}
+}
+return len(p), nil
+}
+
+// whitespaceFilter is an io.Writer collecting the bytes that are not filtered out
+type whitespaceFilter struct {
+data []byte
+}
+
+func (f *whitespaceFilter) Write(p []byte) (int, error) {
+for _, b := range p {
+if !isFingerprintWhitespace(b) {
+f.data = append(f.data, b)
+}
+}
+return len(p), nil
+}
+
+// Murmur2Hasher computes the CurseForge fingerprint incrementally as an io.Writer
+// Murmur2 mixes the input length into its initial state, so the number of
+// non-whitespace bytes (see CountFingerprintBytes) must be known up front
+// Whitespace written to the hasher is filtered out just like ComputeFingerprint does
+type Murmur2Hasher struct {
+h       uint32
+length  int64
+written int64
+tail    [4]byte
+ntail   int
+}
+
+// NewMurmur2Hasher creates a hasher for input with the given filtered length
+func NewMurmur2Hasher(filteredLength int64) *Murmur2Hasher {
+return &Murmur2Hasher{
+h:      murmur2Seed ^ uint32(filteredLength),
+length: filteredLength,
+}
+}
+
+// Write feeds data into the hash, skipping whitespace bytes
+func (m *Murmur2Hasher) Write(p []byte) (int, error) {
+for _, b := range p {
+if isFingerprintWhitespace(b) {
+continue
+}
+if m.written == m.length {
+return 0, ErrFingerprintLengthMismatch
+}
+m.written++
+
+m.tail[m.ntail] = b
+m.ntail++
+if m.ntail == 4 {
+k := uint32(m.tail[0]) |
+uint32(m.tail[1])<<8 |
+uint32(m.tail[2])<<16 |
+uint32(m.tail[3])<<24
+
+k *= murmur2M
+k ^= k >> uint32(murmur2R)
+k *= murmur2M
+
+m.h *= murmur2M
+m.h ^= k
+m.ntail = 0
+}
+}
+return len(p), nil
+}
+
+// Sum32 returns the Murmur2 hash of the data written so far
+// The result only matches ComputeFingerprint once all declared bytes are written
+func (m *Murmur2Hasher) Sum32() uint32 {
+h := m.h
+
+switch m.ntail {
+case 3:
+h ^= uint32(m.tail[2]) << 16
+fallthrough
+case 2:
+h ^= uint32(m.tail[1]) << 8
+fallthrough
+case 1:
+h ^= uint32(m.tail[0])
+h *= murmur2M
+}
+
+h ^= h >> 13
+h *= murmur2M
+h ^= h >> 15
+
+return h
+}
+
+// Fingerprint returns the CurseForge fingerprint, or ErrFingerprintLengthMismatch
+// if fewer bytes were written than declared
+func (m *Murmur2Hasher) Fingerprint() (int64, error) {
+if m.written != m.length {
+return 0, ErrFingerprintLengthMismatch
+}
+return int64(m.Sum32()), nil
+}
+
 // computeMurmur2 computes the Murmur2 hash used by CurseForge
 func computeMurmur2(data []byte) uint32 {
 length := len(data)
@@ -2,6 +2,11 @@ package curseforge
 
 import (
 "bytes"
+"errors"
+"io"
+"math/rand"
+"os"
+"path/filepath"
 "testing"
 )
 
@@ -166,3 +171,128 @@ if fp1 == fp2 {
 t.Error("Different inputs produced same fingerprint")
 }
 }
+
+// fingerprintTestData returns deterministic pseudo-random data with plenty of whitespace
+func fingerprintTestData(size int) []byte {
+rng := rand.New(rand.NewSource(int64(size)))
+data := make([]byte, size)
+rng.Read(data)
+for i := 0; i < size; i += 7 {
+data[i] = " \t\r\n"[i%4]
+}
+return data
+}
+
+func TestMurmur2HasherMatchesComputeFingerprint(t *testing.T) {
+for _, size := range []int{0, 1, 2, 3, 4, 5, 17, 1024, 65537} {
+data := fingerprintTestData(size)
+expected := ComputeFingerprint(data)
+
+for _, chunk := range []int{1, 3, 4, 1000} {
+length, err := CountFingerprintBytes(bytes.NewReader(data))
+if err != nil {
+t.Fatalf("CountFingerprintBytes failed: %v", err)
+}
+
+hasher := NewMurmur2Hasher(length)
+for i := 0; i < len(data); i += chunk {
+end := i + chunk
+if end > len(data) {
+end = len(data)
+}
+hasher.Write(data[i:end])
+}
+
+fp, err := hasher.Fingerprint()
+if err != nil {
+t.Fatalf("size %d chunk %d: Fingerprint failed: %v", size, chunk, err)
+}
+if fp != expected {
+t.Errorf("size %d chunk %d: streaming fingerprint = %d, want %d", size, chunk, fp, expected)
+}
+}
+}
+}
+
+func TestComputeFingerprintFromReadSeeker(t *testing.T) {
+data := fingerprintTestData(10000)
+reader := bytes.NewReader(append([]byte("header"), data...))
+reader.Seek(int64(len("header")), io.SeekStart)
+
+fp, err := ComputeFingerprintFromReadSeeker(reader)
+if err != nil {
+t.Fatalf("ComputeFingerprintFromReadSeeker failed: %v", err)
+}
+if expected := ComputeFingerprint(data); fp != expected {
+t.Errorf("Fingerprint from read seeker = %d, want %d", fp, expected)
+}
+}
+
+func TestComputeFingerprintFromNonSeekableReader(t *testing.T) {
+data := fingerprintTestData(10000)
+
+fp, err := ComputeFingerprintFromReader(io.MultiReader(bytes.NewReader(data)))
+if err != nil {
+t.Fatalf("ComputeFingerprintFromReader failed: %v", err)
+}
+if expected := ComputeFingerprint(data); fp != expected {
+t.Errorf("Fingerprint from reader = %d, want %d", fp, expected)
+}
+}
+
+func TestComputeFileFingerprint(t *testing.T) {
+data := fingerprintTestData(100000)
+path := filepath.Join(t.TempDir(), "mod.jar")
+if err := os.WriteFile(path, data, 0o644); err != nil {
+t.Fatal(err)
+}
+
+fp, err := ComputeFileFingerprint(path)
+if err != nil {
+t.Fatalf("ComputeFileFingerprint failed: %v", err)
+}
+if expected := ComputeFingerprint(data); fp != expected {
+t.Errorf("File fingerprint = %d, want %d", fp, expected)
+}
+}
+
+func TestMurmur2HasherLengthMismatch(t *testing.T) {
+short := NewMurmur2Hasher(10)
+short.Write([]byte("abc"))
+if _, err := short.Fingerprint(); !errors.Is(err, ErrFingerprintLengthMismatch) {
+t.Errorf("expected ErrFingerprintLengthMismatch for short input, got %v", err)
+}
+
+long := NewMurmur2Hasher(2)
+if _, err := long.Write([]byte("abc")); !errors.Is(err, ErrFingerprintLengthMismatch) {
+t.Errorf("expected ErrFingerprintLengthMismatch for long input, got %v", err)
+}
+}
+
+const benchmarkFingerprintSize = 16 << 20
+
+func BenchmarkComputeFingerprint(b *testing.B) {
+data := fingerprintTestData(benchmarkFingerprintSize)
+b.SetBytes(int64(len(data)))
+b.ReportAllocs()
+b.ResetTimer()
+
+for i := 0; i < b.N; i++ {
+// Mirrors the previous reader path: a full copy followed by a filtered copy
+buf, _ := io.ReadAll(io.MultiReader(bytes.NewReader(data)))
+ComputeFingerprint(buf)
+}
+}
+
+func BenchmarkComputeFingerprintFromReadSeeker(b *testing.B) {
+data := fingerprintTestData(benchmarkFingerprintSize)
+b.SetBytes(int64(len(data)))
+b.ReportAllocs()
+b.ResetTimer()
+
+for i := 0; i < b.N; i++ {
+if _, err := ComputeFingerprintFromReadSeeker(bytes.NewReader(data)); err != nil {
+b.Fatal(err)
+}
+}
+}
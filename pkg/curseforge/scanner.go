@@ -0,0 +1,270 @@
+package curseforge
+
+import (
+"fmt"
+"io/fs"
+"path/filepath"
+"runtime"
+"sort"
+"strings"
+"sync"
+)
+
+// Default values used by ScanDirectory
+const (
+DefaultScanBatchSize = 500
+)
+
+// DefaultScanExtensions lists the file extensions scanned when none are given
+var DefaultScanExtensions = []string{".jar", ".zip"}
+
+// ScanOptions configures ScanDirectory
+type ScanOptions struct {
+GameID     int      // game to match against, defaults to GameIDMinecraft
+Workers    int      // concurrent fingerprint workers, defaults to runtime.NumCPU()
+BatchSize  int      // fingerprints per API request, defaults to DefaultScanBatchSize
+Extensions []string // extensions to scan (case-insensitive), defaults to DefaultScanExtensions
+Recursive  bool     // descend into subdirectories
+
+// Cache, when set, is consulted before hashing and updated with new results
+Cache *FingerprintCache
+}
+
+// ScanMatch describes a local file identified on CurseForge
+type ScanMatch struct {
+Path        string
+Fingerprint int64
+Mod         *Mod
+File        File
+LatestFiles []File
+}
+
+// ScanReport is the result of ScanDirectory
+// Every scanned path appears in exactly one of Matches, PartialMatches,
+// Unmatched or Errors
+type ScanReport struct {
+Matches        map[string]ScanMatch
+PartialMatches map[string]ScanMatch
+Unmatched      []string
+Errors         map[string]error
+}
+
+// fingerprintResult is produced by the fingerprint workers
+type fingerprintResult struct {
+path        string
+fingerprint int64
+err         error
+}
+
+// ScanDirectory fingerprints the files in dir and identifies them on CurseForge
+// Fingerprints are computed by a bounded worker pool and matched in batches via
+// GetFingerprintsMatchesByGameID; the owning mods are fetched with GetMods
+func ScanDirectory(server CurseForgeServer, dir string, options ScanOptions) (*ScanReport, error) {
+options = options.withDefaults()
+
+paths, err := collectScanPaths(dir, options)
+if err != nil {
+return nil, err
+}
+
+report := &ScanReport{
+Matches:        make(map[string]ScanMatch),
+PartialMatches: make(map[string]ScanMatch),
+Errors:         make(map[string]error),
+}
+
+pathsByFingerprint := make(map[int64][]string)
+var fingerprints []int64
+fingerprint := ComputeFileFingerprint
+if options.Cache != nil {
+fingerprint = options.Cache.Fingerprint
+}
+
+for _, result := range fingerprintFiles(paths, options.Workers, fingerprint) {
+if result.err != nil {
+report.Errors[result.path] = result.err
+continue
+}
+if _, seen := pathsByFingerprint[result.fingerprint]; !seen {
+fingerprints = append(fingerprints, result.fingerprint)
+}
+pathsByFingerprint[result.fingerprint] = append(pathsByFingerprint[result.fingerprint], result.path)
+}
+
+contextLogger.Trace(fmt.Sprintf("scanned %d files, %d unique fingerprints", len(paths), len(fingerprints)))
+
+matched := make(map[int64]bool)
+for start := 0; start < len(fingerprints); start += options.BatchSize {
+end := start + options.BatchSize
+if end > len(fingerprints) {
+end = len(fingerprints)
+}
+
+result, err := GetFingerprintsMatchesByGameID(server, options.GameID, fingerprints[start:end])
+if err != nil {
+return nil, fmt.Errorf("failed to match fingerprints: %w", err)
+}
+
+for _, match := range result.ExactMatches {
+fingerprint := match.File.FileFingerprint
+for _, path := range pathsByFingerprint[fingerprint] {
+report.Matches[path] = ScanMatch{Path: path, Fingerprint: fingerprint, File: match.File, LatestFiles: match.LatestFiles}
+}
+matched[fingerprint] = true
+}
+
+for _, match := range result.PartialMatches {
+for _, fingerprint := range partialMatchFingerprints(match.File, pathsByFingerprint) {
+if matched[fingerprint] {
+continue
+}
+for _, path := range pathsByFingerprint[fingerprint] {
+report.PartialMatches[path] = ScanMatch{Path: path, Fingerprint: fingerprint, File: match.File, LatestFiles: match.LatestFiles}
+}
+matched[fingerprint] = true
+}
+}
+}
+
+for fingerprint, fingerprintPaths := range pathsByFingerprint {
+if !matched[fingerprint] {
+report.Unmatched = append(report.Unmatched, fingerprintPaths...)
+}
+}
+sort.Strings(report.Unmatched)
+
+if err := attachScanMods(server, report); err != nil {
+return nil, err
+}
+return report, nil
+}
+
+// withDefaults fills in unset scan options
+func (o ScanOptions) withDefaults() ScanOptions {
+if o.GameID == 0 {
+o.GameID = GameIDMinecraft
+}
+if o.Workers <= 0 {
+o.Workers = runtime.NumCPU()
+}
+if o.BatchSize <= 0 {
+o.BatchSize = DefaultScanBatchSize
+}
+if len(o.Extensions) == 0 {
+o.Extensions = DefaultScanExtensions
+}
+return o
+}
+
+// collectScanPaths lists the files in dir that should be fingerprinted
+func collectScanPaths(dir string, options ScanOptions) ([]string, error) {
+var paths []string
+err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+if err != nil {
+return err
+}
+if entry.IsDir() {
+if path != dir && !options.Recursive {
+return filepath.SkipDir
+}
+return nil
+}
+if !entry.Type().IsRegular() || !hasScanExtension(path, options.Extensions) {
+return nil
+}
+paths = append(paths, path)
+return nil
+})
+if err != nil {
+return nil, fmt.Errorf("failed to walk directory: %w", err)
+}
+return paths, nil
+}
+
+// hasScanExtension reports whether path ends with one of the extensions
+func hasScanExtension(path string, extensions []string) bool {
+ext := filepath.Ext(path)
+for _, allowed := range extensions {
+if strings.EqualFold(ext, allowed) {
+return true
+}
+}
+return false
+}
+
+// fingerprintFiles fingerprints paths with a bounded pool of workers
+// Results are returned in the same order as paths
+func fingerprintFiles(paths []string, workers int, fingerprint func(path string) (int64, error)) []fingerprintResult {
+results := make([]fingerprintResult, len(paths))
+jobs := make(chan int)
+
+var wg sync.WaitGroup
+for i := 0; i < workers; i++ {
+wg.Add(1)
+go func() {
+defer wg.Done()
+for idx := range jobs {
+fp, err := fingerprint(paths[idx])
+results[idx] = fingerprintResult{path: paths[idx], fingerprint: fp, err: err}
+}
+}()
+}
+
+for idx := range paths {
+jobs <- idx
+}
+close(jobs)
+wg.Wait()
+
+return results
+}
+
+// partialMatchFingerprints returns the local fingerprints a partially matched
+// file corresponds to, checking the file and its module fingerprints
+func partialMatchFingerprints(file File, pathsByFingerprint map[int64][]string) []int64 {
+var fingerprints []int64
+if _, ok := pathsByFingerprint[file.FileFingerprint]; ok {
+fingerprints = append(fingerprints, file.FileFingerprint)
+}
+for _, module := range file.Modules {
+if _, ok := pathsByFingerprint[module.Fingerprint]; ok && module.Fingerprint != file.FileFingerprint {
+fingerprints = append(fingerprints, module.Fingerprint)
+}
+}
+return fingerprints
+}
+
+// attachScanMods fetches the mods owning the matched files and links them
+func attachScanMods(server CurseForgeServer, report *ScanReport) error {
+seen := make(map[int]bool)
+var modIDs []int
+for _, matches := range []map[string]ScanMatch{report.Matches, report.PartialMatches} {
+for _, match := range matches {
+if !seen[match.File.ModID] {
+seen[match.File.ModID] = true
+modIDs = append(modIDs, match.File.ModID)
+}
+}
+}
+if len(modIDs) == 0 {
+return nil
+}
+
+mods, err := GetMods(server, modIDs)
+if err != nil {
+return fmt.Errorf("failed to get matched mods: %w", err)
+}
+
+modsByID := make(map[int]*Mod, len(mods))
+for i := range mods {
+modsByID[mods[i].ID] = &mods[i]
+}
+
+for _, matches := range []map[string]ScanMatch{report.Matches, report.PartialMatches} {
+for path, match := range matches {
+match.Mod = modsByID[match.File.ModID]
+matches[path] = match
+}
+}
+return nil
+}
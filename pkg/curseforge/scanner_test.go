@@ -0,0 +1,75 @@
+package curseforge
+
+import (
+"encoding/json"
+"net/http"
+"net/http/httptest"
+"os"
+"path/filepath"
+"testing"
+)
+
+func TestScanDirectory(t *testing.T) {
+dir := t.TempDir()
+files := map[string][]byte{
+"jei.jar":           []byte("jei contents"),
+"copy-of-jei.jar":   []byte("jei contents"),
+"sodium.jar":        []byte("sodium contents"),
+"local.jar":         []byte("local build"),
+"notes.txt":         []byte("ignored"),
+"nested/nested.jar": []byte("not scanned"),
+}
+for name, data := range files {
+path := filepath.Join(dir, name)
+os.MkdirAll(filepath.Dir(path), 0o755)
+if err := os.WriteFile(path, data, 0o644); err != nil {
+t.Fatal(err)
+}
+}
+
+jeiFingerprint := ComputeFingerprint(files["jei.jar"])
+sodiumFingerprint := ComputeFingerprint(files["sodium.jar"])
+
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch r.URL.Path {
+case "/v1/fingerprints/432":
+var request FingerprintsMatchesRequest
+json.NewDecoder(r.Body).Decode(&request)
+if len(request.Fingerprints) != 3 {
+t.Errorf("expected 3 unique fingerprints, got %d", len(request.Fingerprints))
+}
+json.NewEncoder(w).Encode(Response[FingerprintMatchesResult]{Data: FingerprintMatchesResult{
+ExactMatches: []FingerprintMatch{{ID: 238222, File: File{ID: 1, ModID: 238222, FileFingerprint: jeiFingerprint}}},
+PartialMatches: []FingerprintMatch{{ID: 394468, File: File{ID: 2, ModID: 394468, FileFingerprint: 99,
+Modules: []FileModule{{Name: "sodium", Fingerprint: sodiumFingerprint}}}}},
+}})
+case "/v1/mods":
+json.NewEncoder(w).Encode(Response[[]Mod]{Data: []Mod{{ID: 238222, Name: "JEI"}, {ID: 394468, Name: "Sodium"}}})
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+defer server.Close()
+
+report, err := ScanDirectory(NewServerWithURL("key", server.URL), dir, ScanOptions{Workers: 2})
+if err != nil {
+t.Fatalf("ScanDirectory failed: %v", err)
+}
+
+for _, name := range []string{"jei.jar", "copy-of-jei.jar"} {
+match, ok := report.Matches[filepath.Join(dir, name)]
+if !ok || match.Mod == nil || match.Mod.Name != "JEI" {
+t.Errorf("%s: match = %+v", name, match)
+}
+}
+if match, ok := report.PartialMatches[filepath.Join(dir, "sodium.jar")]; !ok || match.Mod == nil || match.Mod.Name != "Sodium" {
+t.Errorf("sodium.jar: partial match = %+v", match)
+}
+if len(report.Unmatched) != 1 || report.Unmatched[0] != filepath.Join(dir, "local.jar") {
+t.Errorf("Unmatched = %q", report.Unmatched)
+}
+if len(report.Errors) != 0 {
+t.Errorf("Errors = %v", report.Errors)
+}
+}
@@ -0,0 +1,197 @@
+package curseforge
+
+import (
+"archive/zip"
+"errors"
+"fmt"
+"regexp"
+"strings"
+)
+
+// ErrNoServerPack is returned by FindServerPack when a modpack file has no server pack
+var ErrNoServerPack = errors.New("modpack file has no server pack")
+
+// ServerPackInstallation is the result of InstallServerPack
+// Exactly one of ServerPack and Modpack is set
+type ServerPackInstallation struct {
+// ServerPack is the server pack that was extracted, and Files the paths
+// extracted from it relative to the server directory
+ServerPack *File
+Files      []string
+
+// Modpack is the server tree built from the client manifest when the
+// modpack has no server pack
+Modpack *ModpackInstallation
+}
+
+// FindServerPack returns the server pack of a modpack file
+//
+// The file's ServerPackFileID is followed when set. Otherwise the mod's files
+// are searched for a server pack whose ParentProjectFileID is the file, then
+// for the one unlinked server pack with the same Minecraft version and the
+// same modpack version in its display name or file name. ErrNoServerPack is
+// returned when none is found or the version search is ambiguous.
+func FindServerPack(server CurseForgeServer, file File) (*File, error) {
+if file.IsServerPack != nil && *file.IsServerPack {
+return &file, nil
+}
+if file.ServerPackFileID != nil && *file.ServerPackFileID != 0 {
+serverPack, err := GetModFile(server, file.ModID, *file.ServerPackFileID)
+if err != nil {
+return nil, fmt.Errorf("failed to get server pack %d: %w", *file.ServerPackFileID, err)
+}
+return serverPack, nil
+}
+
+files, err := GetAllModFiles(server, file.ModID, &GetModFilesRequest{})
+if err != nil {
+return nil, fmt.Errorf("failed to list files of mod %d: %w", file.ModID, err)
+}
+if serverPack := matchServerPack(file, files); serverPack != nil {
+return serverPack, nil
+}
+return nil, ErrNoServerPack
+}
+
+// packVersionPattern matches version numbers such as 2.1.0 in display and file names
+var packVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// matchServerPack picks the server pack of file among the files of its mod
+func matchServerPack(file File, files []File) *File {
+var unlinked []*File
+for i := range files {
+candidate := &files[i]
+if candidate.IsServerPack == nil || !*candidate.IsServerPack || !candidate.IsAvailable {
+continue
+}
+if candidate.ParentProjectFileID == nil || *candidate.ParentProjectFileID == 0 {
+unlinked = append(unlinked, candidate)
+} else if *candidate.ParentProjectFileID == file.ID {
+return candidate
+}
+}
+
+versions := minecraftVersionSet(file)
+packVersion := modpackVersion(file, versions)
+if packVersion == "" {
+return nil
+}
+
+var match *File
+for _, candidate := range unlinked {
+candidateVersions := minecraftVersionSet(*candidate)
+if !sharesMinecraftVersion(candidateVersions, versions) {
+continue
+}
+for version := range versions {
+candidateVersions[version] = true
+}
+if modpackVersion(*candidate, candidateVersions) != packVersion {
+continue
+}
+if match != nil {
+contextLogger.Trace(fmt.Sprintf("Server packs %d and %d both match file %d", match.ID, candidate.ID, file.ID))
+return nil
+}
+match = candidate
+}
+return match
+}
+
+// minecraftVersionSet returns the lowercase Minecraft versions of file
+func minecraftVersionSet(file File) map[string]bool {
+versions := make(map[string]bool)
+for _, version := range file.MinecraftVersions() {
+versions[strings.ToLower(version.Raw)] = true
+}
+return versions
+}
+
+// sharesMinecraftVersion reports whether two version sets have a version in common
+func sharesMinecraftVersion(a map[string]bool, b map[string]bool) bool {
+for version := range a {
+if b[version] {
+return true
+}
+}
+return false
+}
+
+// modpackVersion returns the last version number in the display name, or else
+// the file name, of a modpack file that is not one of the Minecraft versions
+func modpackVersion(file File, minecraftVersions map[string]bool) string {
+for _, name := range []string{file.DisplayName, file.FileName} {
+matches := packVersionPattern.FindAllString(name, -1)
+for i := len(matches) - 1; i >= 0; i-- {
+if !minecraftVersions[matches[i]] {
+return matches[i]
+}
+}
+}
+return ""
+}
+
+// InstallServerPack installs the server side of a modpack file into serverDir
+//
+// The server pack found by FindServerPack is downloaded with hash verification
+// into a temporary file and extracted; a single top-level folder wrapping its
+// content is stripped. When the modpack has no server pack, the client modpack
+// is installed with ServerOnly set instead, dropping client-only mods, resource
+// packs and shaders. With DryRun the server pack is only resolved: ServerPack
+// is reported but nothing is downloaded, so Files stays empty.
+func InstallServerPack(server CurseForgeServer, modpack File, serverDir string, options ModpackInstallOptions) (*ServerPackInstallation, error) {
+serverPack, err := FindServerPack(server, modpack)
+if errors.Is(err, ErrNoServerPack) {
+contextLogger.Trace(fmt.Sprintf("No server pack for file %d, building the server from the client manifest", modpack.ID))
+options.ServerOnly = true
+installation, err := InstallModpackFile(server, modpack, serverDir, options)
+if err != nil {
+return nil, err
+}
+return &ServerPackInstallation{Modpack: installation}, nil
+}
+if err != nil {
+return nil, err
+}
+
+installation := &ServerPackInstallation{ServerPack: serverPack}
+if options.DryRun {
+return installation, nil
+}
+
+archive, closeArchive, err := downloadZipToTemp(*serverPack, options.Download.Download)
+if err != nil {
+return nil, fmt.Errorf("failed to get server pack: %w", err)
+}
+defer closeArchive()
+
+files, err := archiveFilesUnder(archive, archiveRootPrefix(archive))
+if err != nil {
+return nil, err
+}
+
+fsys := orOSFS(options.FS)
+for _, entry := range files {
+if err := extractZipEntry(fsys, entry.file, joinPath(fsys, serverDir, entry.relative)); err != nil {
+return installation, fmt.Errorf("failed to extract %s: %w", entry.relative, err)
+}
+installation.Files = append(installation.Files, entry.relative)
+}
+return installation, nil
+}
+
+// archiveRootPrefix returns "dir/" when every entry of the zip is inside the same top-level folder
+func archiveRootPrefix(archive *zip.Reader) string {
+root := ""
+for _, entry := range archive.File {
+dir, _, nested := strings.Cut(entry.Name, "/")
+if !nested || (root != "" && dir != root) {
+return ""
+}
+root = dir
+}
+if root == "" {
+return ""
+}
+return root + "/"
+}
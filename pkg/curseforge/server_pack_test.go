@@ -0,0 +1,194 @@
+package curseforge
+
+import (
+"errors"
+"reflect"
+"testing"
+)
+
+func TestMatchServerPack(t *testing.T) {
+isServerPack := true
+parentID := 100
+otherParentID := 99
+client := File{ID: 100, ModID: 1, DisplayName: "Pack 2.1.0", FileName: "Pack-1.20.1-2.1.0.zip", GameVersions: []string{"1.20.1", "Forge"}}
+serverPack := func(id int, name string, version string) File {
+return File{ID: id, ModID: 1, IsAvailable: true, IsServerPack: &isServerPack, DisplayName: name, GameVersions: []string{version}}
+}
+
+linked := serverPack(5, "Pack Server 1.0.0", "1.19.2")
+linked.ParentProjectFileID = &parentID
+otherParent := serverPack(7, "Pack Server 2.1.0", "1.20.1")
+otherParent.ParentProjectFileID = &otherParentID
+unavailable := serverPack(6, "Pack Server 2.1.0", "1.20.1")
+unavailable.IsAvailable = false
+byFileName := serverPack(8, "", "1.20.1")
+byFileName.FileName = "Pack-Server-1.20.1-2.1.0.zip"
+
+tests := []struct {
+name  string
+files []File
+want  int
+}{
+{"parent link wins", []File{serverPack(1, "Pack Server 2.1.0", "1.20.1"), linked}, 5},
+{"same versions", []File{serverPack(1, "Pack Server 2.0.0", "1.20.1"), serverPack(2, "Pack Server 2.1.0", "1.20.1")}, 2},
+{"pack version from the file name", []File{byFileName}, 8},
+{"other pack version", []File{serverPack(1, "Pack Server 2.0.0", "1.20.1")}, 0},
+{"other minecraft version", []File{serverPack(1, "Pack Server 2.1.0", "1.19.2")}, 0},
+{"no pack version", []File{serverPack(1, "Pack Server", "1.20.1")}, 0},
+{"ambiguous", []File{serverPack(1, "Pack Server 2.1.0", "1.20.1"), serverPack(2, "Pack Server 2.1.0 hotfix", "1.20.1")}, 0},
+{"linked to another file", []File{otherParent}, 0},
+{"not a server pack", []File{{ID: 1, IsAvailable: true, DisplayName: "Pack Server 2.1.0", GameVersions: []string{"1.20.1"}}}, 0},
+{"unavailable", []File{unavailable}, 0},
+}
+for _, tt := range tests {
+t.Run(tt.name, func(t *testing.T) {
+got := matchServerPack(client, tt.files)
+if tt.want == 0 {
+if got != nil {
+t.Errorf("matchServerPack = %d, want none", got.ID)
+}
+return
+}
+if got == nil || got.ID != tt.want {
+t.Errorf("matchServerPack = %+v, want file %d", got, tt.want)
+}
+})
+}
+}
+
+func TestFindServerPack(t *testing.T) {
+isServerPack := true
+serverPackID := 200
+parentID := 101
+server := newModpackTestServer(t)
+server.files = []File{
+{ID: 200, ModID: 1, IsAvailable: true, IsServerPack: &isServerPack, FileName: "server.zip"},
+{ID: 300, ModID: 2, IsAvailable: true, IsServerPack: &isServerPack, GameVersions: []string{"1.20.1"}, ParentProjectFileID: &parentID},
+{ID: 400, ModID: 4, IsAvailable: true, IsServerPack: &isServerPack, DisplayName: "Pack Server 3.0", GameVersions: []string{"1.20.1"}},
+}
+cf := NewServerWithURL("key", server.URL)
+
+found, err := FindServerPack(cf, File{ID: 100, ModID: 1, ServerPackFileID: &serverPackID})
+if err != nil || found.ID != 200 {
+t.Errorf("FindServerPack by ServerPackFileID = %+v, %v", found, err)
+}
+found, err = FindServerPack(cf, File{ID: 101, ModID: 2, GameVersions: []string{"1.20.1"}})
+if err != nil || found.ID != 300 {
+t.Errorf("FindServerPack by search = %+v, %v", found, err)
+}
+found, err = FindServerPack(cf, server.files[1])
+if err != nil || found.ID != 300 {
+t.Errorf("a server pack should be its own server pack: %+v, %v", found, err)
+}
+if _, err := FindServerPack(cf, File{ID: 102, ModID: 3}); !errors.Is(err, ErrNoServerPack) {
+t.Errorf("expected ErrNoServerPack, got %v", err)
+}
+if _, err := FindServerPack(cf, File{ID: 103, ModID: 2, GameVersions: []string{"1.20.1"}}); !errors.Is(err, ErrNoServerPack) {
+t.Errorf("a server pack of another file was matched: %v", err)
+}
+found, err = FindServerPack(cf, File{ID: 104, ModID: 4, DisplayName: "Pack 3.0", GameVersions: []string{"1.20.1"}})
+if err != nil || found.ID != 400 {
+t.Errorf("FindServerPack by version = %+v, %v", found, err)
+}
+}
+
+func TestInstallServerPack(t *testing.T) {
+zipData := modpackTestZip(t, map[string]string{
+"Pack-Server-1.0/":                     "",
+"Pack-Server-1.0/mods/a.jar":           "a",
+"Pack-Server-1.0/config/a.cfg":         "a=1",
+"Pack-Server-1.0/startserver.sh":       "java -jar forge.jar",
+"Pack-Server-1.0/user_jvm_args.txt":    "-Xmx4G",
+"Pack-Server-1.0/world/placeholder.md": "",
+})
+server := newModpackTestServer(t)
+server.content = map[string][]byte{"server.zip": zipData}
+serverPack := modpackTestFile(server, 200, 1, "server.zip", zipData)
+isServerPack := true
+serverPack.IsServerPack = &isServerPack
+server.files = []File{serverPack}
+cf := NewServerWithURL("key", server.URL)
+
+serverPackID := 200
+modpack := File{ID: 100, ModID: 1, ServerPackFileID: &serverPackID}
+
+fsys := NewMemFS()
+plan, err := InstallServerPack(cf, modpack, "server", ModpackInstallOptions{FS: fsys, DryRun: true})
+if err != nil {
+t.Fatalf("InstallServerPack dry run failed: %v", err)
+}
+if len(fsys.Files()) != 0 || len(plan.Files) != 0 || plan.ServerPack.ID != 200 {
+t.Errorf("dry run = %+v, wrote %v", plan, fsys.Files())
+}
+if downloads := server.downloads.Load(); downloads != 0 {
+t.Errorf("dry run downloaded the server pack %d times", downloads)
+}
+
+installation, err := InstallServerPack(cf, modpack, "server", ModpackInstallOptions{FS: fsys})
+if err != nil {
+t.Fatalf("InstallServerPack failed: %v", err)
+}
+if installation.Modpack != nil || len(installation.Files) != 5 {
+t.Errorf("server pack should be extracted, not built from the manifest: %+v", installation)
+}
+want := []string{"server/config/a.cfg", "server/mods/a.jar", "server/startserver.sh", "server/user_jvm_args.txt", "server/world/placeholder.md"}
+if !reflect.DeepEqual(fsys.Files(), want) {
+t.Errorf("Files = %v, want %v", fsys.Files(), want)
+}
+}
+
+func TestInstallServerPackFallback(t *testing.T) {
+server := newModpackTestServer(t)
+server.content = map[string][]byte{
+"common.jar": fingerprintTestData(2000),
+"client.jar": fingerprintTestData(3000),
+}
+server.mods = []Mod{
+{ID: 10, ClassID: ClassIDMods},
+{ID: 11, ClassID: ClassIDMods},
+{ID: 12, ClassID: ClassIDShaders},
+}
+common := modpackTestFile(server, 1000, 10, "common.jar", server.content["common.jar"])
+common.GameVersions = []string{"1.20.1", "Forge", "Client", "Server"}
+client := modpackTestFile(server, 1100, 11, "client.jar", server.content["client.jar"])
+client.GameVersions = []string{"1.20.1", "Forge", "Client"}
+shader := File{ID: 1200, ModID: 12, FileName: "shader.zip", DownloadURL: server.URL + "/download/shader.zip"}
+
+manifest := NewModpackManifest("Pack", "1.0", "me", "1.20.1")
+manifest.SetModLoader(ModLoaderForge, "47.2.0")
+manifest.AddFile(10, 1000, true)
+manifest.AddFile(11, 1100, true)
+manifest.AddFile(12, 1200, true)
+zipData := modpackTestZip(t, map[string]string{
+"manifest.json":          mustEncodeManifest(t, manifest),
+"overrides/config/a.cfg": "a=1",
+})
+server.content["pack.zip"] = zipData
+modpack := modpackTestFile(server, 100, 1, "pack.zip", zipData)
+modpack.GameVersions = []string{"1.20.1"}
+server.files = []File{modpack, common, client, shader}
+cf := NewServerWithURL("key", server.URL)
+
+fsys := NewMemFS()
+installation, err := InstallServerPack(cf, modpack, "server", ModpackInstallOptions{FS: fsys})
+if err != nil {
+t.Fatalf("InstallServerPack failed: %v", err)
+}
+if installation.ServerPack != nil || installation.Modpack == nil {
+t.Fatalf("expected a server built from the manifest, got %+v", installation)
+}
+if !installation.Modpack.Complete() {
+t.Errorf("installation incomplete: %v", installation.Modpack.Err())
+}
+var clientOnly []int
+for _, file := range installation.Modpack.ClientOnly {
+clientOnly = append(clientOnly, file.ID)
+}
+if !reflect.DeepEqual(clientOnly, []int{1100, 1200}) {
+t.Errorf("ClientOnly = %v, want [1100 1200]", clientOnly)
+}
+want := []string{"server/config/a.cfg", "server/mods/common.jar"}
+if !reflect.DeepEqual(fsys.Files(), want) {
+t.Errorf("Files = %v, want %v", fsys.Files(), want)
+}
+}
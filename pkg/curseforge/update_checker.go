@@ -0,0 +1,151 @@
+package curseforge
+
+import (
+"fmt"
+"sort"
+"time"
+)
+
+// UpdateOptions configures CheckUpdates
+type UpdateOptions struct {
+// Channel is the least stable release type offered as an update, e.g.
+// ReleaseTypeBeta offers releases and betas. Zero uses the release type
+// of each installed file, so a release is only updated to a release.
+Channel FileReleaseType
+
+// GameVersion and ModLoader override what is read from each installed
+// file, e.g. when the instance is being moved to a new Minecraft version
+GameVersion string
+ModLoader   ModLoaderType
+}
+
+// FileUpdate is a newer compatible file for an installed file
+type FileUpdate struct {
+Mod         Mod
+Current     File
+Latest      File
+ReleaseType FileReleaseType // of Latest
+FileDate    time.Time       // of Latest
+GameVersion string          // the game version Latest was matched for
+}
+
+// UpdatePlan is the result of CheckUpdates
+// Updates and UpToDate are sorted by mod ID
+type UpdatePlan struct {
+Updates  []FileUpdate
+UpToDate []File
+Errors   map[int]error // by mod ID
+}
+
+// CheckUpdates finds newer compatible files for installed files
+//
+// For every installed file the newest file for the same game version and
+// loader, at or above the release channel, is picked with SelectFile. The
+// game version is the newest the installed file supports, with its other
+// versions as fallbacks. Server packs are never offered. An error is only
+// returned when the mods cannot be fetched; per-mod failures are in Errors.
+func CheckUpdates(server CurseForgeServer, installed []File, options UpdateOptions) (*UpdatePlan, error) {
+plan := &UpdatePlan{Errors: make(map[int]error)}
+if len(installed) == 0 {
+return plan, nil
+}
+
+modIDs := make([]int, 0, len(installed))
+seen := make(map[int]bool)
+for _, file := range installed {
+if !seen[file.ModID] {
+seen[file.ModID] = true
+modIDs = append(modIDs, file.ModID)
+}
+}
+
+contextLogger.Trace(fmt.Sprintf("Checking updates for %d mods", len(modIDs)))
+mods, err := GetMods(server, modIDs)
+if err != nil {
+return nil, fmt.Errorf("failed to get mods: %w", err)
+}
+modsByID := make(map[int]Mod, len(mods))
+for _, mod := range mods {
+modsByID[mod.ID] = mod
+}
+
+for _, file := range installed {
+mod, ok := modsByID[file.ModID]
+if !ok {
+plan.Errors[file.ModID] = fmt.Errorf("mod %d not found", file.ModID)
+continue
+}
+
+selection, err := SelectFile(server, mod, updateConstraints(file, options))
+if err != nil {
+plan.Errors[file.ModID] = err
+continue
+}
+if !selection.Found() || !isNewerFile(*selection.File, file) {
+plan.UpToDate = append(plan.UpToDate, file)
+continue
+}
+
+plan.Updates = append(plan.Updates, FileUpdate{
+Mod:         mod,
+Current:     file,
+Latest:      *selection.File,
+ReleaseType: selection.File.ReleaseType,
+FileDate:    selection.File.FileDate,
+GameVersion: selection.GameVersion,
+})
+}
+
+sort.Slice(plan.Updates, func(i, j int) bool { return plan.Updates[i].Current.ModID < plan.Updates[j].Current.ModID })
+sort.Slice(plan.UpToDate, func(i, j int) bool { return plan.UpToDate[i].ModID < plan.UpToDate[j].ModID })
+return plan, nil
+}
+
+// updateConstraints derives the file constraints for updating an installed file
+func updateConstraints(file File, options UpdateOptions) FileConstraints {
+constraints := FileConstraints{
+GameVersion:        options.GameVersion,
+ModLoader:          options.ModLoader,
+MaxReleaseType:     options.Channel,
+ExcludeServerPacks: true,
+}
+if constraints.MaxReleaseType == 0 {
+constraints.MaxReleaseType = file.ReleaseType
+}
+
+if constraints.GameVersion == "" {
+versions := file.MinecraftVersions()
+sort.SliceStable(versions, func(i, j int) bool {
+result, ok := versions[i].Compare(versions[j])
+return ok && result > 0
+})
+for i, version := range versions {
+if i == 0 {
+constraints.GameVersion = version.Raw
+} else {
+constraints.FallbackGameVersions = append(constraints.FallbackGameVersions, version.Raw)
+}
+}
+}
+
+if constraints.ModLoader == ModLoaderAny {
+// A file declaring several loaders is updated to a file supporting one of them
+if loaders := file.Loaders(); len(loaders) == 1 {
+constraints.ModLoader = loaders[0]
+} else {
+constraints.ModLoaders = loaders
+}
+}
+return constraints
+}
+
+// isNewerFile reports whether candidate is a newer file than current
+func isNewerFile(candidate, current File) bool {
+if candidate.ID == current.ID {
+return false
+}
+if candidate.FileDate.Equal(current.FileDate) {
+return candidate.ID > current.ID
+}
+return candidate.FileDate.After(current.FileDate)
+}
@@ -0,0 +1,105 @@
+package curseforge
+
+import (
+"encoding/json"
+"fmt"
+"net/http"
+"net/http/httptest"
+"testing"
+"time"
+)
+
+func TestCheckUpdates(t *testing.T) {
+installed := []File{
+{ID: 10, ModID: 1, IsAvailable: true, ReleaseType: ReleaseTypeRelease, GameVersions: []string{"1.20.1", "1.20", "Forge"}},
+{ID: 20, ModID: 2, IsAvailable: true, ReleaseType: ReleaseTypeRelease, GameVersions: []string{"1.20.1", "Forge"}},
+{ID: 30, ModID: 3, IsAvailable: true, ReleaseType: ReleaseTypeRelease, GameVersions: []string{"1.20.1", "Forge"}},
+{ID: 40, ModID: 4, IsAvailable: true, ReleaseType: ReleaseTypeRelease, GameVersions: []string{"1.20.1", "Forge"}},
+}
+for i := range installed {
+installed[i].FileDate = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+}
+
+newer := selectorTestFile(11, 5, ReleaseTypeRelease, "1.20.1", "Forge")
+newerFabric := selectorTestFile(12, 9, ReleaseTypeRelease, "1.20.1", "Fabric")
+newerBeta := selectorTestFile(31, 6, ReleaseTypeBeta, "1.20.1", "Forge")
+newerBeta.ModID = 3
+
+mods := []Mod{
+{ID: 1, Name: "One", LatestFiles: []File{newer, newerFabric}},
+{ID: 2, Name: "Two", LatestFiles: []File{installed[1]}},
+{ID: 3, Name: "Three", LatestFiles: []File{installed[2], newerBeta}},
+}
+
+server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+switch r.URL.Path {
+case "/v1/mods":
+json.NewEncoder(w).Encode(Response[[]Mod]{Data: mods})
+default:
+t.Errorf("unexpected request %s", r.URL.Path)
+w.WriteHeader(http.StatusNotFound)
+}
+}))
+defer server.Close()
+cf := NewServerWithURL("key", server.URL)
+
+plan, err := CheckUpdates(cf, installed, UpdateOptions{})
+if err != nil {
+t.Fatalf("CheckUpdates failed: %v", err)
+}
+
+if len(plan.Updates) != 1 {
+t.Fatalf("Updates = %+v, want one update for mod 1", plan.Updates)
+}
+update := plan.Updates[0]
+if update.Current.ID != 10 || update.Latest.ID != 11 || update.Mod.Name != "One" || update.GameVersion != "1.20.1" {
+t.Errorf("update = %+v", update)
+}
+if update.ReleaseType != ReleaseTypeRelease || !update.FileDate.Equal(newer.FileDate) {
+t.Errorf("update release type %v, date %v", update.ReleaseType, update.FileDate)
+}
+if len(plan.UpToDate) != 2 || plan.UpToDate[0].ID != 20 || plan.UpToDate[1].ID != 30 {
+t.Errorf("UpToDate = %+v, want files 20 and 30", plan.UpToDate)
+}
+if plan.Errors[4] == nil || len(plan.Errors) != 1 {
+t.Errorf("Errors = %v, want mod 4 not found", plan.Errors)
+}
+
+plan, err = CheckUpdates(cf, installed[2:3], UpdateOptions{Channel: ReleaseTypeBeta})
+if err != nil {
+t.Fatalf("CheckUpdates failed: %v", err)
+}
+if len(plan.Updates) != 1 || plan.Updates[0].Latest.ID != 31 {
+t.Errorf("beta channel should offer file 31, got %+v", plan.Updates)
+}
+}
+
+func TestUpdateConstraints(t *testing.T) {
+file := File{ReleaseType: ReleaseTypeBeta, GameVersions: []string{"1.20", "1.20.1", "NeoForge", "Client"}}
+
+constraints := updateConstraints(file, UpdateOptions{})
+if constraints.GameVersion != "1.20.1" || len(constraints.FallbackGameVersions) != 1 || constraints.FallbackGameVersions[0] != "1.20" {
+t.Errorf("versions = %q %q, want newest first", constraints.GameVersion, constraints.FallbackGameVersions)
+}
+if constraints.ModLoader != ModLoaderNeoForge || constraints.MaxReleaseType != ReleaseTypeBeta || !constraints.ExcludeServerPacks {
+t.Errorf("constraints = %+v", constraints)
+}
+
+constraints = updateConstraints(file, UpdateOptions{GameVersion: "1.21", ModLoader: ModLoaderFabric, Channel: ReleaseTypeRelease})
+if constraints.GameVersion != "1.21" || constraints.FallbackGameVersions != nil || constraints.ModLoader != ModLoaderFabric || constraints.MaxReleaseType != ReleaseTypeRelease {
+t.Errorf("overridden constraints = %+v", constraints)
+}
+
+multiLoader := File{ID: 1, ReleaseType: ReleaseTypeRelease, GameVersions: []string{"1.20.1", "Forge", "NeoForge"}}
+constraints = updateConstraints(multiLoader, UpdateOptions{})
+if constraints.ModLoader != ModLoaderAny || fmt.Sprint(constraints.ModLoaders) != fmt.Sprint([]ModLoaderType{ModLoaderForge, ModLoaderNeoForge}) {
+t.Errorf("multi-loader constraints = %+v", constraints)
+}
+candidates := []File{
+selectorTestFile(2, 10, ReleaseTypeRelease, "1.20.1", "NeoForge"),
+selectorTestFile(3, 20, ReleaseTypeRelease, "1.20.1", "Fabric"),
+}
+if selection := SelectFileFrom(candidates, constraints); !selection.Found() || selection.File.ID != 2 {
+t.Errorf("multi-loader update selected %+v, want the NeoForge file: %s", selection.File, selection.Explain())
+}
+}